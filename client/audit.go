@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one request the client made to change the Console.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Operation is "create", "update" or "delete"
+	Operation string `json:"operation"`
+	// ObjectType is the kind of Console object changed, e.g. "user"
+	ObjectType string `json:"object_type"`
+	ObjectID   string `json:"object_id"`
+	// Before and After are the object before and after the change, with
+	// passwords redacted. They are null when the object didn't exist.
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	// Status is the HTTP status the Console answered with, zero when the
+	// request failed before getting an answer
+	Status int `json:"status"`
+	// Error is set when the change failed. A 200 Status with an Error means
+	// the Console accepted the change but it could not be read back, After is
+	// then what was sent.
+	Error string `json:"error,omitempty"`
+	// ResourceType is the type of the Terraform resource that made the
+	// change, see WithResourceType
+	ResourceType string `json:"resource_type,omitempty"`
+	Project      string `json:"project,omitempty"`
+}
+
+// AuditHook is called after every request that changes the Console.
+type AuditHook func(AuditRecord)
+
+type resourceTypeKey struct{}
+
+// WithResourceType records `resourceType`, e.g. "twistlock_user", as the
+// origin of the changes made with the returned context.
+func WithResourceType(ctx context.Context, resourceType string) context.Context {
+	return context.WithValue(ctx, resourceTypeKey{}, resourceType)
+}
+
+// AuditLog appends AuditRecords to a file as JSON lines.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog opens the file at `path` for appending, creating it if needed.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open audit log: %s", err)
+	}
+	return &AuditLog{file: file}, nil
+}
+
+// Record appends r to the log. Each record is written with a single write so
+// that concurrent providers appending to the same file don't interleave.
+func (l *AuditLog) Record(r AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the log file.
+func (l *AuditLog) Close() error {
+	return l.file.Close()
+}
+
+// auditing reports whether changes to the Console are recorded, methods use it
+// to skip reading the object's state before a change.
+func (c *Client) auditing() bool {
+	return c.auditHook != nil
+}
+
+// audit records a change to the Console made with ctx. `status` and `err`
+// are the outcome of the request that made the change.
+func (c *Client) audit(ctx context.Context, operation, objectType, objectID string, before, after interface{}, status int, err error) {
+	if !c.auditing() {
+		return
+	}
+
+	r := AuditRecord{
+		Time:       time.Now().UTC(),
+		Operation:  operation,
+		ObjectType: objectType,
+		ObjectID:   objectID,
+		Before:     auditPayload(before),
+		After:      auditPayload(after),
+		Status:     status,
+		Project:    c.project(ctx),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if resourceType, ok := ctx.Value(resourceTypeKey{}).(string); ok {
+		r.ResourceType = resourceType
+	}
+
+	c.auditHook(r)
+}
+
+// auditPayload marshals an object for an AuditRecord with its credentials
+// redacted, nil becomes null.
+func auditPayload(v interface{}) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(redactBody(payload))
+}
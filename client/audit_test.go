@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestAuditRecordsChanges(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("admin", "secret")
+	defer console.Close()
+
+	var records []AuditRecord
+	c := newTestClient(t, Config{
+		Username:  "admin",
+		Password:  "secret",
+		BaseURL:   console.URL(),
+		AuditHook: func(r AuditRecord) { records = append(records, r) },
+	})
+
+	ctx := WithResourceType(context.Background(), "twistlock_user")
+	_, err := c.CreateUser(ctx, &model.User{Username: "bob", Password: "hunter2", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	_, err = c.UpdateUser(ctx, &model.User{Username: "bob", Role: model.RoleAdmin, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	assert.Nil(c.DeleteUser(ctx, &model.User{Username: "bob"}))
+	assert.NotNil(c.DeleteUser(ctx, &model.User{Username: "bob"}))
+	console.InjectFault(fakeconsole.ServerError("POST", "/users"))
+	_, err = c.CreateUser(ctx, &model.User{Username: "alice", Password: "hunter2", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.NotNil(err)
+
+	if !assert.Len(records, 5) {
+		return
+	}
+
+	assert.Equal("create", records[0].Operation)
+	assert.Equal("user", records[0].ObjectType)
+	assert.Equal("bob", records[0].ObjectID)
+	assert.Equal("twistlock_user", records[0].ResourceType)
+	assert.Equal(http.StatusOK, records[0].Status)
+	assert.Equal("null", string(records[0].Before))
+	assert.Contains(string(records[0].After), `"role":"user"`)
+
+	assert.Equal("update", records[1].Operation)
+	assert.Contains(string(records[1].Before), `"role":"user"`)
+	assert.Contains(string(records[1].After), `"role":"admin"`)
+
+	assert.Equal("delete", records[2].Operation)
+	assert.Contains(string(records[2].Before), `"role":"admin"`)
+	assert.Equal("null", string(records[2].After))
+	assert.Empty(records[2].Error)
+
+	assert.Equal(http.StatusInternalServerError, records[3].Status)
+	assert.Equal("Failed to delete user bob: DELETE /api/v1/users/bob returned 500 Internal Server Error: user bob does not exist", records[3].Error)
+
+	// Failed changes record what was sent, without the password
+	assert.Equal(http.StatusInternalServerError, records[4].Status)
+	assert.Contains(string(records[4].After), `"password":"<redacted>"`)
+	assert.NotContains(string(records[4].After), "hunter2")
+}
+
+func TestAuditRecordsFailedReadBack(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("admin", "secret")
+	defer console.Close()
+
+	var records []AuditRecord
+	c := newTestClient(t, Config{
+		Username:  "admin",
+		Password:  "secret",
+		BaseURL:   console.URL(),
+		AuditHook: func(r AuditRecord) { records = append(records, r) },
+	})
+
+	// The policy is updated but reading it back fails
+	console.InjectFault(fakeconsole.ServerError("GET", "/policies/cve"))
+	_, err := c.UpdateCVEPolicy(context.Background(), &model.CVEPolicy{Rules: []model.CVEPolicyRule{}})
+	assert.NotNil(err)
+
+	if !assert.Len(records, 1) {
+		return
+	}
+	assert.Equal(http.StatusOK, records[0].Status)
+	assert.Equal(err.Error(), records[0].Error)
+	assert.Contains(string(records[0].After), `"_id":"cve"`)
+}
+
+func TestAuditLogAppendsJSONLines(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	for _, id := range []string{"alice", "bob"} {
+		log, err := OpenAuditLog(path)
+		if !assert.Nil(err) {
+			return
+		}
+		assert.Nil(log.Record(AuditRecord{Operation: "delete", ObjectType: "user", ObjectID: id}))
+		assert.Nil(log.Close())
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	if !assert.Len(lines, 2) {
+		return
+	}
+	var r AuditRecord
+	assert.Nil(json.Unmarshal([]byte(lines[1]), &r))
+	assert.Equal("bob", r.ObjectID)
+
+	info, err := os.Stat(path)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+}
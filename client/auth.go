@@ -0,0 +1,159 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var authenticatePath = "/authenticate"
+
+// defaultTokenLifetime is assumed when the token issued by the Console does
+// not carry a readable expiry claim.
+const defaultTokenLifetime = 30 * time.Minute
+
+// tokenExpirySkew is how long before its expiry a cached token is replaced,
+// so that a token does not expire while a request is in flight.
+const tokenExpirySkew = time.Minute
+
+type authenticateRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type authenticateResponse struct {
+	Token string `json:"token"`
+}
+
+// bearerToken returns the cached token, fetching a new one from the Console
+// when there is no cached token or it is about to expire.
+func (c *Client) bearerToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.token != "" && time.Now().Add(tokenExpirySkew).Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	token, err := c.newToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token
+	c.tokenExpiry = tokenExpiry(token, time.Now())
+	return c.token, nil
+}
+
+// invalidateToken discards the cached token if it is still `token`. A token
+// that was already replaced by a concurrent request is left alone.
+func (c *Client) invalidateToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.token == token {
+		c.token = ""
+		c.tokenExpiry = time.Time{}
+	}
+}
+
+// newToken obtains a new token, from the credentials source when there is
+// one and otherwise by exchanging the configured username and password.
+// c.tokenMu must be held.
+func (c *Client) newToken(ctx context.Context) (string, error) {
+	if c.credentialsSource == nil {
+		return c.authenticate(ctx, c.username, c.password)
+	}
+
+	creds, err := c.credentialsSource(ctx)
+	if err != nil {
+		return "", err
+	}
+	if creds.Username != "" {
+		c.username = creds.Username
+	}
+	if creds.Token != "" {
+		return creds.Token, nil
+	}
+	return c.authenticate(ctx, creds.Username, creds.Password)
+}
+
+// user returns the username the client logs in as, which is only known once
+// a credentials source was called.
+func (c *Client) user() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.username
+}
+
+// authenticate exchanges a username and password for a token.
+func (c *Client) authenticate(ctx context.Context, username, password string) (string, error) {
+	url := c.apiURL() + authenticatePath
+	credentials, err := json.Marshal(authenticateRequest{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(credentials))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Logging in changes nothing on the Console, so it's safe to resend
+	resp, err := c.withFailover(req, true, c.http.Do)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", newAPIError("authenticate as "+username, resp)
+	}
+
+	auth := authenticateResponse{}
+
+	decoder := json.NewDecoder(resp.Body)
+	if err = decoder.Decode(&auth); err != nil {
+		return "", err
+	}
+	if auth.Token == "" {
+		return "", fmt.Errorf("Failed to authenticate as %s: no token in response", username)
+	}
+
+	return auth.Token, nil
+}
+
+// tokenExpiry reads the `exp` claim from a JWT. Tokens that cannot be parsed
+// are assumed to be valid for defaultTokenLifetime from `now`.
+func tokenExpiry(token string, now time.Time) time.Time {
+	fallback := now.Add(defaultTokenLifetime)
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fallback
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return fallback
+	}
+
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return fallback
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
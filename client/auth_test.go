@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1500000000, 0)
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1500003600}`))
+	assert.Equal(time.Unix(1500003600, 0), tokenExpiry("header."+payload+".signature", now))
+
+	assert.Equal(now.Add(defaultTokenLifetime), tokenExpiry("not-a-jwt", now))
+	assert.Equal(now.Add(defaultTokenLifetime), tokenExpiry("header.!!!.signature", now))
+}
+
+func TestTokenAuthCachesAndRefreshesToken(t *testing.T) {
+	assert := assert.New(t)
+
+	issued := 0
+	revoked := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, defaultAPIPath) {
+		case authenticatePath:
+			issued++
+			fmt.Fprintf(w, `{"token": "token-%d"}`, issued)
+		case userPath:
+			auth := r.Header.Get("Authorization")
+			if _, _, ok := r.BasicAuth(); ok || revoked[auth] {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `[{"_id": "1", "username": "bob"}]`)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, Config{
+		Username:   "admin",
+		Password:   "secret",
+		BaseURL:    server.URL,
+		AuthMethod: AuthMethodToken,
+	})
+
+	user, found, err := c.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal(model.User{ID: "1", Username: "bob"}, user)
+
+	// Drop the cached user list so that every read reaches the server
+	c.users.forProject("").invalidate()
+	_, _, err = c.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.Equal(1, issued, "token should be reused between requests")
+
+	revoked["Bearer token-1"] = true
+	c.users.forProject("").invalidate()
+	_, found, err = c.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal(2, issued, "token should be refreshed after a 401")
+}
+
+func TestAccessKeyAuthUsesTenantPath(t *testing.T) {
+	assert := assert.New(t)
+
+	var credentials struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/us-2-123456789/api/v1" + authenticatePath:
+			json.NewDecoder(r.Body).Decode(&credentials)
+			fmt.Fprint(w, `{"token": "saas-token"}`)
+		case "/us-2-123456789/api/v1" + userPath:
+			if r.Header.Get("Authorization") != "Bearer saas-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `[{"_id": "1", "username": "bob"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, Config{
+		AccessKey: "access-key-id",
+		SecretKey: "secret-key",
+		BaseURL:   server.URL + "/us-2-123456789",
+		// Access keys can only be exchanged for a token
+		AuthMethod: AuthMethodBasic,
+	})
+
+	_, found, err := c.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal("access-key-id", credentials.Username)
+	assert.Equal("secret-key", credentials.Password)
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Credentials log in to the Console, either with a username and password or
+// with a token that was already issued.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// CredentialsSource fetches credentials from outside the provider
+// configuration, e.g. a secret broker. It is called whenever a new token is
+// needed, so it may return rotated credentials.
+type CredentialsSource func(ctx context.Context) (Credentials, error)
+
+// CommandCredentials returns a CredentialsSource that runs `command`, the
+// program followed by its arguments, and reads Credentials as JSON from its
+// standard output.
+func CommandCredentials(command []string) CredentialsSource {
+	return func(ctx context.Context) (Credentials, error) {
+		if len(command) == 0 {
+			return Credentials{}, fmt.Errorf("credentials_command is empty")
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return Credentials{}, fmt.Errorf("Credentials command %s failed: %s: %s", command[0], err, strings.TrimSpace(stderr.String()))
+		}
+
+		var creds Credentials
+		if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+			return Credentials{}, fmt.Errorf("Credentials command %s printed invalid JSON: %s", command[0], err)
+		}
+		if creds.Token == "" && (creds.Username == "" || creds.Password == "") {
+			return Credentials{}, fmt.Errorf("Credentials command %s printed neither a token nor a username and password", command[0])
+		}
+		return creds, nil
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsSourceIsCalledForEachToken(t *testing.T) {
+	assert := assert.New(t)
+
+	revoked := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth == "" || revoked[auth] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"_id": "cve", "policyType": "cve"}`)
+	}))
+	defer server.Close()
+
+	calls := 0
+	c := newTestClient(t, Config{
+		BaseURL: server.URL,
+		CredentialsSource: func(context.Context) (Credentials, error) {
+			calls++
+			return Credentials{Username: "broker", Token: fmt.Sprintf("token-%d", calls)}, nil
+		},
+	})
+
+	_, err := c.ReadCVEPolicy(context.Background())
+	assert.Nil(err)
+	_, err = c.ReadCVEPolicy(context.Background())
+	assert.Nil(err)
+	assert.Equal(1, calls, "the token should be reused until it is rejected")
+	assert.Equal("broker", c.user())
+
+	revoked["Bearer token-1"] = true
+	_, err = c.ReadCVEPolicy(context.Background())
+	assert.Nil(err)
+	assert.Equal(2, calls)
+}
+
+// TestCredentialsCommandHelper is run as the credentials command by
+// TestCommandCredentials.
+func TestCredentialsCommandHelper(t *testing.T) {
+	if os.Getenv("TWISTLOCK_CREDENTIALS_HELPER") == "" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	switch args[1] {
+	case "fail":
+		fmt.Fprint(os.Stderr, "agent is not running")
+		os.Exit(1)
+	default:
+		fmt.Print(args[1])
+		os.Exit(0)
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	assert := assert.New(t)
+	os.Setenv("TWISTLOCK_CREDENTIALS_HELPER", "1")
+	defer os.Unsetenv("TWISTLOCK_CREDENTIALS_HELPER")
+
+	command := func(output string) CredentialsSource {
+		return CommandCredentials([]string{os.Args[0], "-test.run=TestCredentialsCommandHelper", "--", output})
+	}
+
+	creds, err := command(`{"username": "admin", "password": "secret"}`)(context.Background())
+	assert.Nil(err)
+	assert.Equal(Credentials{Username: "admin", Password: "secret"}, creds)
+
+	_, err = command("fail")(context.Background())
+	assert.NotNil(err)
+	assert.True(strings.HasSuffix(err.Error(), ": exit status 1: agent is not running"), err.Error())
+
+	_, err = command("not json")(context.Background())
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "printed invalid JSON")
+
+	_, err = command(`{"username": "admin"}`)(context.Background())
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "printed neither a token nor a username and password")
+
+	_, err = CommandCredentials(nil)(context.Background())
+	assert.EqualError(err, "credentials_command is empty")
+}
@@ -2,9 +2,9 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
 	"github.com/circleci/terraform-provider-twistlock/model"
@@ -12,8 +12,19 @@ import (
 
 var cvePolicyPath = "/policies/cve"
 
-func (c *Client) UpdateCVEPolicy(p *model.CVEPolicy) (model.CVEPolicy, error) {
-	url := c.baseURL + cvePolicyPath
+func (c *Client) UpdateCVEPolicy(ctx context.Context, p *model.CVEPolicy) (model.CVEPolicy, error) {
+	if err := c.checkWritable("update CVE policy"); err != nil {
+		return model.CVEPolicy{}, err
+	}
+
+	var before interface{}
+	if c.auditing() {
+		if policy, err := c.ReadCVEPolicy(ctx); err == nil {
+			before = policy
+		}
+	}
+
+	url := c.apiURL() + cvePolicyPath
 	p.PolicyType = "cve"
 	p.ID = "cve"
 	policyJson, err := json.Marshal(p)
@@ -26,45 +37,45 @@ func (c *Client) UpdateCVEPolicy(p *model.CVEPolicy) (model.CVEPolicy, error) {
 		return model.CVEPolicy{}, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.http.Do(req)
+	resp, err := c.do(ctx, req)
 	if err != nil {
+		c.audit(ctx, "update", "cve_policy", p.ID, before, p, 0, err)
 		return model.CVEPolicy{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return model.CVEPolicy{}, fmt.Errorf("Failed to update CVE policy: %s", string(body))
+		err := newAPIError("update CVE policy", resp)
+		c.audit(ctx, "update", "cve_policy", p.ID, before, p, resp.StatusCode, err)
+		return model.CVEPolicy{}, err
 	}
 
-	policy, err := c.ReadCVEPolicy()
+	policy, err := c.ReadCVEPolicy(ctx)
 	if err != nil {
-		return model.CVEPolicy{}, fmt.Errorf("CVE policy update failed, could not fetch after update: %s", err)
+		err = fmt.Errorf("CVE policy update failed, could not fetch after update: %s", err)
+		c.audit(ctx, "update", "cve_policy", p.ID, before, p, resp.StatusCode, err)
+		return model.CVEPolicy{}, err
 	}
 
+	c.audit(ctx, "update", "cve_policy", p.ID, before, policy, resp.StatusCode, nil)
 	return policy, nil
 }
 
-func (c *Client) ReadCVEPolicy() (model.CVEPolicy, error) {
-	url := c.baseURL + cvePolicyPath
+func (c *Client) ReadCVEPolicy(ctx context.Context) (model.CVEPolicy, error) {
+	url := c.apiURL() + cvePolicyPath
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return model.CVEPolicy{}, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.http.Do(req)
+	resp, err := c.do(ctx, req)
 	if err != nil {
 		return model.CVEPolicy{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return model.CVEPolicy{}, fmt.Errorf("Failed to read CVE policy: %s", string(body))
+		return model.CVEPolicy{}, newAPIError("read CVE policy", resp)
 	}
 
 	cvePolicy := model.CVEPolicy{}
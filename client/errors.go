@@ -0,0 +1,117 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// requestIDHeader is the response header the Console uses to identify a
+// request in its own logs.
+const requestIDHeader = "X-Request-Id"
+
+// APIError is returned when the Twistlock Console answers a request with an
+// unexpected HTTP status.
+type APIError struct {
+	// Op describes what the client was trying to do, e.g. "read users"
+	Op         string
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the error reported by the Console, if it sent one
+	Message   string
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("Failed to %s: %s %s returned %d %s",
+		e.Op, e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request ID %s)", e.RequestID)
+	}
+	return msg
+}
+
+// ReadOnlyError is returned by methods that would change the Console when the
+// client is read-only.
+type ReadOnlyError struct {
+	// Op describes what the client refused to do, e.g. "delete user bob"
+	Op string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("Refusing to %s: the Twistlock provider is read-only", e.Op)
+}
+
+// IsReadOnly reports whether err is a ReadOnlyError.
+func IsReadOnly(err error) bool {
+	_, ok := err.(*ReadOnlyError)
+	return ok
+}
+
+// newAPIError builds an APIError from an unsuccessful response, consuming the
+// response body.
+func newAPIError(op string, resp *http.Response) *APIError {
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	return &APIError{
+		Op:         op,
+		Method:     resp.Request.Method,
+		Path:       resp.Request.URL.Path,
+		StatusCode: resp.StatusCode,
+		Message:    errorMessage(body),
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+}
+
+// errorMessage extracts the Console's error message from a response body.
+// The Console reports errors as `{"err": "..."}`; anything else is returned
+// as-is.
+func errorMessage(body []byte) string {
+	parsed := struct {
+		Err     string `json:"err"`
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if parsed.Err != "" {
+			return parsed.Err
+		}
+		if parsed.Message != "" {
+			return parsed.Message
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func hasStatus(err error, status int) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == status
+}
+
+// IsNotFound reports whether err is an APIError for a missing object.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an APIError for an object that already
+// exists or was modified concurrently.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsUnauthorized reports whether err is an APIError caused by missing or
+// rejected credentials.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an APIError caused by the configured
+// user lacking permission for the operation.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestErrorMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("user does not exist", errorMessage([]byte(`{"err":"user does not exist"}`)))
+	assert.Equal("bad request", errorMessage([]byte(`{"message":"bad request"}`)))
+	assert.Equal("gateway timeout", errorMessage([]byte("gateway timeout\n")))
+	assert.Equal("", errorMessage(nil))
+}
+
+func TestAPIErrorPredicates(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsNotFound(&APIError{StatusCode: http.StatusNotFound}))
+	assert.True(IsConflict(&APIError{StatusCode: http.StatusConflict}))
+	assert.True(IsUnauthorized(&APIError{StatusCode: http.StatusUnauthorized}))
+	assert.True(IsForbidden(&APIError{StatusCode: http.StatusForbidden}))
+
+	assert.False(IsNotFound(&APIError{StatusCode: http.StatusInternalServerError}))
+	assert.False(IsNotFound(errors.New("not found")))
+	assert.False(IsNotFound(nil))
+}
+
+func TestDeleteUserReturnsAPIError(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(requestIDHeader, "abc123")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"err":"user bob does not exist"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL})
+	err := c.DeleteUser(context.Background(), &model.User{Username: "bob"})
+
+	assert.True(IsNotFound(err))
+	assert.Equal(&APIError{
+		Op:         "delete user bob",
+		Method:     "DELETE",
+		Path:       "/api/v1/users/bob",
+		StatusCode: http.StatusNotFound,
+		Message:    "user bob does not exist",
+		RequestID:  "abc123",
+	}, err)
+	assert.EqualError(err, "Failed to delete user bob: DELETE /api/v1/users/bob returned 404 Not Found: user bob does not exist (request ID abc123)")
+}
+
+func TestReadOnlyRefusesChanges(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Read-only client sent %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"_id": "cve", "policyType": "cve"}`)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, ReadOnly: true})
+	bob := &model.User{Username: "bob"}
+
+	_, err := c.CreateUser(ctx, bob)
+	assert.EqualError(err, "Refusing to create user bob: the Twistlock provider is read-only")
+	_, err = c.UpdateUser(ctx, bob)
+	assert.EqualError(err, "Refusing to update user bob: the Twistlock provider is read-only")
+	err = c.DeleteUser(ctx, bob)
+	assert.True(IsReadOnly(err))
+	_, err = c.UpdateCVEPolicy(ctx, &model.CVEPolicy{})
+	assert.EqualError(err, "Refusing to update CVE policy: the Twistlock provider is read-only")
+
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+}
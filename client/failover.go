@@ -0,0 +1,123 @@
+package client
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// consoleURL returns the URL of the Console endpoint currently in use.
+func (c *Client) consoleURL() string {
+	c.endpointMu.Lock()
+	defer c.endpointMu.Unlock()
+	return c.endpoints[c.active]
+}
+
+// apiURL returns the base URL of the API of the Console endpoint currently in
+// use.
+func (c *Client) apiURL() string {
+	return c.consoleURL() + c.apiPath
+}
+
+// withFailover sends req with `send`, failing over to the next Console
+// endpoint in order when the current one has a connection error or returns a
+// 5xx response. Requests that may change the Console, i.e. unless
+// `idempotent` is set, only fail over when the connection couldn't be made,
+// since otherwise the first Console may already have applied them. The
+// endpoint that answers is used for later requests. When every endpoint fails
+// the last failure is returned.
+func (c *Client) withFailover(req *http.Request, idempotent bool, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	c.endpointMu.Lock()
+	start := c.active
+	c.endpointMu.Unlock()
+
+	for i := 0; ; i++ {
+		n := (start + i) % len(c.endpoints)
+		attempt, err := c.rebase(req, c.endpoints[n])
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := send(attempt)
+		failed := failedOver(req, idempotent, resp, err)
+		if !failed || i == len(c.endpoints)-1 {
+			if i > 0 && !failed {
+				c.setActive(n)
+			}
+			return resp, err
+		}
+
+		next := c.endpoints[(n+1)%len(c.endpoints)]
+		if err != nil {
+			log.Printf("[DEBUG] %s %s failed on Console %s: %s, failing over to %s", req.Method, req.URL.Path, c.endpoints[n], err, next)
+		} else {
+			log.Printf("[DEBUG] %s %s returned %d on Console %s, failing over to %s", req.Method, req.URL.Path, resp.StatusCode, c.endpoints[n], next)
+			drain(resp)
+		}
+	}
+}
+
+// failedOver reports whether a response means the endpoint is unhealthy and
+// req can be resent to the next one. Cancelled requests aren't the endpoint's
+// fault. Timeouts and 5xx responses leave the outcome of requests that aren't
+// idempotent unknown, so only dial errors fail those over.
+func failedOver(req *http.Request, idempotent bool, resp *http.Response, err error) bool {
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		return false
+	case !idempotent:
+		return err != nil && isDialError(err)
+	case err != nil:
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// isDialError reports whether err means no connection to the Console could be
+// made, e.g. because it refused it, so a request can't have reached it.
+func isDialError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// setActive makes endpoint n the one used for later requests.
+func (c *Client) setActive(n int) {
+	c.endpointMu.Lock()
+	defer c.endpointMu.Unlock()
+	if c.active != n {
+		c.active = n
+		log.Printf("[DEBUG] Using Twistlock Console %s", c.endpoints[n])
+	}
+}
+
+// rebase returns a copy of req sent to Console endpoint `to` instead of the
+// endpoint it was built for.
+func (c *Client) rebase(req *http.Request, to string) (*http.Request, error) {
+	from := ""
+	for _, endpoint := range c.endpoints {
+		rest := strings.TrimPrefix(req.URL.String(), endpoint)
+		if rest != req.URL.String() && strings.HasPrefix(rest, "/") && len(endpoint) > len(from) {
+			from = endpoint
+		}
+	}
+	if from == to || from == "" {
+		return req, nil
+	}
+
+	u, err := url.Parse(to + strings.TrimPrefix(req.URL.String(), from))
+	if err != nil {
+		return nil, err
+	}
+	rebased, err := rewindRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	rebased.URL = u
+	rebased.Host = ""
+	return rebased, nil
+}
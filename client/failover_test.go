@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+// newFailoverConsoles starts a primary and a secondary fake console and a
+// client failing over from the primary to the secondary.
+func newFailoverConsoles(t *testing.T, config Config) (*fakeconsole.Console, *fakeconsole.Console, *Client) {
+	primary := fakeconsole.New("admin", "secret")
+	secondary := fakeconsole.New("admin", "secret")
+
+	config.Username = "admin"
+	config.Password = "secret"
+	config.BaseURL = primary.URL()
+	config.FailoverURLs = []string{secondary.URL()}
+	return primary, secondary, newTestClient(t, config)
+}
+
+func TestFailoverOnConnectionError(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	primary, secondary, c := newFailoverConsoles(t, Config{AuthMethod: AuthMethodToken})
+	primary.Close()
+	defer secondary.Close()
+
+	_, err := c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	assert.Equal(secondary.ConsoleURL(), c.consoleURL(), "the healthy endpoint should be remembered")
+	assert.Equal(1, secondary.Requests("POST", "/authenticate"))
+	assert.Equal(1, secondary.Requests("GET", "/policies/cve"))
+
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	assert.Equal(2, secondary.Requests("GET", "/policies/cve"))
+}
+
+func TestFailoverOnServerError(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	primary := fakeconsole.New("admin", "secret")
+	defer primary.Close()
+	primary.InjectFault(fakeconsole.ServerError("", ""))
+	secondary := fakeconsole.New("admin", "secret")
+	defer secondary.Close()
+
+	c := newTestClient(t, Config{
+		Username:     "admin",
+		Password:     "secret",
+		BaseURL:      primary.ConsoleURL(),
+		FailoverURLs: []string{secondary.ConsoleURL()},
+	})
+
+	_, err := c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	assert.Equal(1, primary.Requests("GET", "/policies/cve"))
+	assert.Equal(1, secondary.Requests("GET", "/policies/cve"))
+	assert.Equal(secondary.URL(), c.apiURL())
+
+	// When every endpoint fails the last failure is returned
+	secondary.Close()
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.EqualError(err, "Failed to read CVE policy: GET /api/v1/policies/cve returned 500 Internal Server Error: internal server error")
+}
+
+func TestFailoverURLsMustShareAPIPath(t *testing.T) {
+	_, err := NewClient(Config{
+		BaseURL:      "https://console-a:8083/api/v1",
+		FailoverURLs: []string{"https://console-b:8083"},
+	})
+	assert.EqualError(t, err, "Console URLs must all include the same API path, https://console-b:8083 doesn't match https://console-a:8083/api/v1")
+}
+
+func TestFailoverPostOnConnectionRefused(t *testing.T) {
+	assert := assert.New(t)
+
+	primary, secondary, c := newFailoverConsoles(t, Config{})
+	primary.Close()
+	defer secondary.Close()
+
+	_, err := c.CreateUser(context.Background(), &model.User{Username: "alice", Password: "password", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	assert.Equal(1, secondary.Requests("POST", "/users"))
+	_, found := secondary.User("alice")
+	assert.True(found)
+}
+
+func TestNoFailoverPostOnServerError(t *testing.T) {
+	assert := assert.New(t)
+
+	primary, secondary, c := newFailoverConsoles(t, Config{})
+	defer primary.Close()
+	defer secondary.Close()
+	primary.InjectFault(fakeconsole.ServerError("POST", "/users"))
+
+	_, err := c.CreateUser(context.Background(), &model.User{Username: "alice", Password: "password", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Error(err)
+	assert.Equal(1, primary.Requests("POST", "/users"))
+	assert.Equal(0, secondary.Requests("POST", "/users"), "the primary may have created the user")
+	assert.Equal(primary.ConsoleURL(), c.consoleURL())
+}
+
+func TestNoFailoverPostOnTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	primary, secondary, c := newFailoverConsoles(t, Config{RequestTimeout: 100 * time.Millisecond})
+	defer primary.Close()
+	defer secondary.Close()
+	primary.InjectFault(&fakeconsole.Fault{Method: "POST", Path: "/users", Latency: time.Second})
+
+	_, err := c.CreateUser(context.Background(), &model.User{Username: "alice", Password: "password", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Error(err)
+	assert.Equal(0, secondary.Requests("POST", "/users"), "the primary may have created the user")
+}
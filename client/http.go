@@ -1,27 +1,286 @@
 package client
 
 import (
-	"crypto/tls"
+	"context"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+// AuthMethod selects how the client authenticates against the Twistlock
+// Console.
+type AuthMethod string
+
+const (
+	// AuthMethodBasic sends the username and password with every request.
+	AuthMethodBasic AuthMethod = "basic"
+	// AuthMethodToken exchanges the username and password for a bearer token
+	// via the Console's /authenticate endpoint and sends the token instead.
+	AuthMethodToken AuthMethod = "token"
 )
 
+func (a *AuthMethod) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "basic", "":
+		*a = AuthMethodBasic
+	case "token":
+		*a = AuthMethodToken
+	default:
+		return fmt.Errorf("Invalid AuthMethod: %s", string(text))
+	}
+	return nil
+}
+
+var _ model.Console = (*Client)(nil)
+
+// Config holds the settings used to build a Client.
+type Config struct {
+	Username string
+	Password string
+	// AccessKey and SecretKey are Prisma Cloud access key credentials, they
+	// replace Username and Password and always authenticate with a token
+	AccessKey string
+	SecretKey string
+	// CredentialsSource replaces Username and Password when set, and always
+	// authenticates with a token
+	CredentialsSource CredentialsSource
+	// BaseURL is the Console's URL, including the tenant path segment for
+	// Prisma Cloud Compute SaaS. An API path such as /api/v1 pins the API
+	// version, otherwise DetectVersion picks it
+	BaseURL string
+	// FailoverURLs are tried in order when the Console at BaseURL has a
+	// connection error or returns a 5xx response
+	FailoverURLs  []string
+	SkipTLSVerify bool
+	// CACertPEM replaces the system roots when verifying the Console's
+	// certificate
+	CACertPEM string
+	// ClientCertPEM and ClientKeyPEM are presented to Consoles that require
+	// mutual TLS
+	ClientCertPEM string
+	ClientKeyPEM  string
+	// MinTLSVersion is a crypto/tls version constant, see ParseTLSVersion
+	MinTLSVersion uint16
+	AuthMethod    AuthMethod
+	// MaxRetries is how many times a request that failed transiently is
+	// retried, zero disables retries
+	MaxRetries int
+	// RetryMaxWait caps the wait between two attempts of a request
+	RetryMaxWait time.Duration
+	// RequestTimeout bounds each attempt of a request, zero means no limit
+	RequestTimeout time.Duration
+	// LogRequests logs every request and response with credentials
+	// redacted, see loggingTransport
+	LogRequests bool
+	// MaxRequestsPerSecond limits the average rate of requests, zero means
+	// no limit
+	MaxRequestsPerSecond float64
+	// MaxConcurrentRequests limits how many requests are in flight at once,
+	// zero means no limit
+	MaxConcurrentRequests int
+	// ReadOnly makes every method that would change the Console return a
+	// ReadOnlyError instead
+	ReadOnly bool
+	// AuditHook, when set, is called with a record of every change made to
+	// the Console, see OpenAuditLog
+	AuditHook AuditHook
+	// Project scopes requests to a Twistlock project unless overridden with
+	// WithProject, empty means the master project
+	Project string
+}
+
+// Client talks to the Twistlock Console API.
 type Client struct {
-	username string
-	password string
-	baseURL  string
-	http     http.Client
+	username   string
+	password   string
+	authMethod AuthMethod
+	http       http.Client
+
+	// credentialsSource replaces username and password, see
+	// Config.CredentialsSource
+	credentialsSource CredentialsSource
+
+	// endpoints are the URLs of the Console, without API path, in failover
+	// order. active is the index of the one in use
+	endpointMu sync.Mutex
+	endpoints  []string
+	active     int
+
+	apiPath string
+	// pinnedAPI is set when the configured URLs chose the API path, which
+	// stops DetectVersion from changing it
+	pinnedAPI bool
+	version   model.Version
+
+	maxRetries   int
+	retryMaxWait time.Duration
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpiry time.Time
+
+	defaultProject string
+	users          userCaches
+
+	// identity is set by Validate
+	identity *identity
+
+	readOnly  bool
+	auditHook AuditHook
 }
 
-func NewClient(username, password, baseURL string, skipTLSVerify bool) Client {
-	return Client{
-		username: username,
-		password: password,
-		baseURL:  baseURL,
+func NewClient(config Config) (*Client, error) {
+	tlsConfig, err := tlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]string, 0, 1+len(config.FailoverURLs))
+	var apiPath string
+	for i, u := range append([]string{config.BaseURL}, config.FailoverURLs...) {
+		consoleURL, path, err := splitConsoleURL(u)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && path != apiPath {
+			return nil, fmt.Errorf("Console URLs must all include the same API path, %s doesn't match %s", u, config.BaseURL)
+		}
+		endpoints = append(endpoints, consoleURL)
+		apiPath = path
+	}
+	pinnedAPI := apiPath != ""
+	if !pinnedAPI {
+		apiPath = defaultAPIPath
+	}
+
+	username, password := config.Username, config.Password
+	authMethod := config.AuthMethod
+	if authMethod == "" {
+		authMethod = AuthMethodBasic
+	}
+	if config.AccessKey != "" {
+		// SaaS Consoles don't accept basic auth, access keys are exchanged
+		// for a token like a username and password would be.
+		username, password = config.AccessKey, config.SecretKey
+		authMethod = AuthMethodToken
+	}
+	if config.CredentialsSource != nil {
+		authMethod = AuthMethodToken
+	}
+	retryMaxWait := config.RetryMaxWait
+	if retryMaxWait <= 0 {
+		retryMaxWait = DefaultRetryMaxWait
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+	if config.LogRequests {
+		transport = &loggingTransport{next: transport}
+	}
+	if config.MaxRequestsPerSecond > 0 || config.MaxConcurrentRequests > 0 {
+		transport = newLimitingTransport(transport, config.MaxRequestsPerSecond, config.MaxConcurrentRequests)
+	}
+
+	return &Client{
+		username:   username,
+		password:   password,
+		endpoints:  endpoints,
+		apiPath:    apiPath,
+		pinnedAPI:  pinnedAPI,
+		authMethod: authMethod,
+
+		credentialsSource: config.CredentialsSource,
+
 		http: http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: skipTLSVerify,
-				},
-			}},
+			Timeout:   config.RequestTimeout,
+			Transport: transport,
+		},
+		maxRetries:     config.MaxRetries,
+		retryMaxWait:   retryMaxWait,
+		defaultProject: config.Project,
+		readOnly:       config.ReadOnly,
+		auditHook:      config.AuditHook,
+	}, nil
+}
+
+// do sends req to the Console, scoped to the project selected by ctx, and
+// retries transient failures until ctx is done.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
+	c.scopeToProject(req)
+	return c.doWithRetry(req)
+}
+
+// checkWritable returns a ReadOnlyError for `op` when the client is
+// read-only. Methods that change the Console call it before any request.
+func (c *Client) checkWritable(op string) error {
+	if c.readOnly {
+		return &ReadOnlyError{Op: op}
+	}
+	return nil
+}
+
+// doAuthenticated authenticates and sends req.
+//
+// When token authentication is in use and the Console rejects the cached
+// token with a 401, the token is discarded and the request is sent once more
+// with a freshly issued token.
+func (c *Client) doAuthenticated(req *http.Request) (*http.Response, error) {
+	if c.authMethod != AuthMethodToken {
+		req.SetBasicAuth(c.username, c.password)
+		return c.http.Do(req)
+	}
+
+	token, err := c.bearerToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	retry, err := rewindRequest(req)
+	if err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	c.invalidateToken(token)
+	token, err = c.bearerToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+
+	return c.http.Do(retry)
+}
+
+// rewindRequest returns a copy of req whose body can be sent again.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := new(http.Request)
+	*retry = *req
+	retry.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		retry.Header[k] = v
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body for %s %s cannot be replayed", req.Method, req.URL.Path)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
 	}
+	retry.Body = body
+	return retry, nil
 }
@@ -0,0 +1,14 @@
+package client
+
+import (
+	"testing"
+)
+
+// newTestClient builds a Client, failing the test if config is invalid.
+func newTestClient(t *testing.T, config Config) *Client {
+	c, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("Could not create client: %s", err)
+	}
+	return c
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket allowing `rate` requests per second on
+// average, with bursts of up to one second's worth of requests.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	burst := math.Max(1, rate)
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until a request may be made or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// limitingTransport bounds the rate of requests to the Console and how many
+// are in flight at once. A request is in flight until the Console's response
+// headers arrive: holding the slot until the body is closed would deadlock
+// callers that send a read while the body of a write is still open. Terraform
+// runs up to 10 resource operations in parallel, which can
+// overwhelm a Console when many users are managed.
+type limitingTransport struct {
+	next http.RoundTripper
+	// limiter is nil when the rate is unlimited
+	limiter *rateLimiter
+	// inFlight is nil when concurrency is unlimited
+	inFlight chan struct{}
+}
+
+func newLimitingTransport(next http.RoundTripper, maxRequestsPerSecond float64, maxConcurrentRequests int) *limitingTransport {
+	t := &limitingTransport{next: next}
+	if maxRequestsPerSecond > 0 {
+		t.limiter = newRateLimiter(maxRequestsPerSecond)
+	}
+	if maxConcurrentRequests > 0 {
+		t.inFlight = make(chan struct{}, maxConcurrentRequests)
+	}
+	return t
+}
+
+func (t *limitingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if t.inFlight != nil {
+		select {
+		case t.inFlight <- struct{}{}:
+			defer func() { <-t.inFlight }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if t.limiter != nil {
+		if err := t.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	l := newRateLimiter(50)
+	start := time.Now()
+	for i := 0; i < 55; i++ {
+		assert.Nil(l.wait(ctx))
+	}
+	// The first 50 requests are a burst, the other 5 wait 20ms each
+	assert.True(time.Since(start) >= 80*time.Millisecond, "took %s", time.Since(start))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(context.Canceled, l.wait(cancelled))
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, `{"_id": "cve", "policyType": "cve"}`)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxConcurrentRequests: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.ReadCVEPolicy(context.Background())
+			assert.Nil(err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(2, maxInFlight)
+}
+
+func TestMaxConcurrentRequestsWrites(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("admin", "admin-password")
+	defer console.Close()
+
+	// Writes read the object back while the write's response is still open,
+	// and invalidate the user cache other writes may be refilling
+	for _, limit := range []int{1, 4} {
+		c := newTestClient(t, Config{
+			Username:              console.Username,
+			Password:              console.Password,
+			BaseURL:               console.URL(),
+			MaxConcurrentRequests: limit,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				username := fmt.Sprintf("user-%d-%d", limit, i)
+				_, err := c.CreateUser(ctx, &model.User{Username: username, Password: "password", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+				assert.Nil(err, "limit %d", limit)
+			}(i)
+		}
+		wg.Wait()
+
+		_, err := c.UpdateCVEPolicy(ctx, &model.CVEPolicy{})
+		assert.Nil(err, "limit %d", limit)
+		cancel()
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const redacted = "<redacted>"
+
+// redactedFields are the JSON fields whose values never appear in logs, e.g.
+// model.User's password and the token returned by /authenticate.
+var redactedFields = map[string]bool{
+	"password": true,
+	"token":    true,
+}
+
+// redactedHeaders are the headers whose values never appear in logs.
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// loggingTransport logs every request made to the Console and its response.
+// The method, URL, status and latency are logged at DEBUG level, headers and
+// bodies at TRACE level with credentials redacted.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = ioutil.ReadAll(body)
+			body.Close()
+		}
+	}
+	log.Printf("[TRACE] Twistlock API request %s %s\n%s", req.Method, req.URL, dump(req.Header, reqBody))
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start)
+	if err != nil {
+		log.Printf("[DEBUG] %s %s failed after %s: %s", req.Method, req.URL, latency, err)
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		log.Printf("[DEBUG] %s %s returned %s in %s, reading the body failed: %s", req.Method, req.URL, resp.Status, latency, err)
+		return resp, nil
+	}
+
+	log.Printf("[DEBUG] %s %s returned %s in %s", req.Method, req.URL, resp.Status, latency)
+	log.Printf("[TRACE] Twistlock API response to %s %s\n%s", req.Method, req.URL, dump(resp.Header, respBody))
+	return resp, nil
+}
+
+// dump formats headers and a body for logging, redacting credentials.
+func dump(header http.Header, body []byte) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, redactHeader(name, value))
+		}
+	}
+	b.WriteString("\n")
+	b.Write(redactBody(body))
+	return b.String()
+}
+
+// redactHeader hides the value of credential headers, keeping the scheme of
+// Authorization headers, e.g. "Bearer <redacted>".
+func redactHeader(name, value string) string {
+	if !redactedHeaders[http.CanonicalHeaderKey(name)] {
+		return value
+	}
+	if scheme := strings.SplitN(value, " ", 2); len(scheme) == 2 {
+		return scheme[0] + " " + redacted
+	}
+	return redacted
+}
+
+// redactBody hides the values of redactedFields anywhere in a JSON body.
+// Bodies that aren't JSON are returned unchanged.
+func redactBody(body []byte) []byte {
+	var v interface{}
+	if len(body) == 0 || json.Unmarshal(body, &v) != nil {
+		return body
+	}
+
+	redactValue(v)
+	var redactedBody bytes.Buffer
+	encoder := json.NewEncoder(&redactedBody)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(redactedBody.Bytes(), []byte("\n"))
+}
+
+func redactValue(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && redactedFields[key] {
+				v[key] = redacted
+				continue
+			}
+			redactValue(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactValue(value)
+		}
+	}
+}
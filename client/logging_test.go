@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestRedactBody(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`{"password":"<redacted>","username":"bob"}`, string(redactBody([]byte(`{"username": "bob", "password": "hunter2"}`))))
+	assert.Equal(`[{"password":"","username":"bob"}]`, string(redactBody([]byte(`[{"username": "bob", "password": ""}]`))))
+	assert.Equal(`{"token":"<redacted>"}`, string(redactBody([]byte(`{"token": "eyJhbGciOi"}`))))
+	assert.Equal("not json", string(redactBody([]byte("not json"))))
+
+	assert.Equal("Bearer <redacted>", redactHeader("authorization", "Bearer eyJhbGciOi"))
+	assert.Equal("<redacted>", redactHeader("Cookie", "session=1"))
+	assert.Equal("application/json", redactHeader("Content-Type", "application/json"))
+}
+
+func TestLogRequestsRedactsCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, defaultAPIPath) {
+		case authenticatePath:
+			fmt.Fprint(w, `{"token": "secret-token"}`)
+		case userPath:
+			fmt.Fprint(w, `[{"_id": "bob", "username": "bob", "role": "user", "authType": "basic"}]`)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, Config{
+		Username:    "admin",
+		Password:    "admin-password",
+		BaseURL:     server.URL,
+		AuthMethod:  AuthMethodToken,
+		LogRequests: true,
+	})
+	_, err := c.CreateUser(context.Background(), &model.User{Username: "bob", Password: "hunter2", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+
+	output := logs.String()
+	for _, secret := range []string{"admin-password", "secret-token", "hunter2"} {
+		assert.NotContains(output, secret)
+	}
+	assert.Contains(output, "[DEBUG] POST "+server.URL+"/api/v1/users returned 200 OK in ")
+	assert.Contains(output, "Authorization: Bearer <redacted>")
+	assert.Contains(output, `"username":"bob"`)
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type projectKey struct{}
+
+// WithProject scopes the requests made with the returned context to a
+// Twistlock project, overriding the client's default project. An empty
+// project selects the client's default.
+func WithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// project returns the project requests made with ctx are scoped to.
+func (c *Client) project(ctx context.Context) string {
+	if project, ok := ctx.Value(projectKey{}).(string); ok && project != "" {
+		return project
+	}
+	return c.defaultProject
+}
+
+// scopeToProject adds the `project` query parameter for the request's
+// project. Requests for the master project carry no parameter.
+func (c *Client) scopeToProject(req *http.Request) {
+	project := c.project(req.Context())
+	if project == "" {
+		return
+	}
+
+	query := req.URL.Query()
+	query.Set("project", project)
+	req.URL.RawQuery = query.Encode()
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryMaxWait is used when a Config does not set RetryMaxWait.
+const DefaultRetryMaxWait = 30 * time.Second
+
+// retryMinWait is the backoff before the first retry, it doubles with every
+// further attempt.
+var retryMinWait = 500 * time.Millisecond
+
+// idempotentMethods may be safely resent after a failure whose outcome on the
+// Console is unknown.
+var idempotentMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+// retryWait decides whether the outcome of attempt number `attempt` (counting
+// from zero) should be retried and how long to wait before doing so.
+func (c *Client) retryWait(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.maxRetries || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		// The request may or may not have reached the Console, unless no
+		// connection could be made
+		return c.backoff(attempt), idempotentMethods[req.Method] || isDialError(err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// Rate-limited requests were not processed, so any method can be
+		// retried
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !idempotentMethods[req.Method] {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		// Retrying sooner than the Console asked is pointless, give up
+		// instead if it wants us to wait longer than we are willing to
+		return wait, wait <= c.retryMaxWait
+	}
+
+	return c.backoff(attempt), true
+}
+
+// backoff returns an exponentially growing wait with jitter, capped at the
+// client's retryMaxWait.
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := retryMinWait << uint(attempt)
+	if wait <= 0 || wait > c.retryMaxWait {
+		wait = c.retryMaxWait
+	}
+
+	// Wait somewhere between half and all of the backoff so that parallel
+	// Terraform operations don't all retry in lockstep
+	half := int64(wait / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// drain discards the rest of a response so its connection can be reused.
+func drain(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// doWithRetry sends req, retrying transient failures within the client's
+// retry budget. Waiting between attempts stops as soon as the request's
+// context is done.
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.withFailover(req, idempotentMethods[req.Method], c.doAuthenticated)
+
+		wait, retry := c.retryWait(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+
+		next, rewindErr := rewindRequest(req)
+		if rewindErr != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Printf("[DEBUG] %s %s failed: %s, retrying in %s", req.Method, req.URL.Path, err, wait)
+		} else {
+			log.Printf("[DEBUG] %s %s returned %d, retrying in %s", req.Method, req.URL.Path, resp.StatusCode, wait)
+			drain(resp)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req = next
+	}
+}
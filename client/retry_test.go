@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func init() {
+	retryMinWait = time.Millisecond
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	wait, ok := parseRetryAfter("120", now)
+	assert.True(ok)
+	assert.Equal(2*time.Minute, wait)
+
+	wait, ok = parseRetryAfter("Tue, 01 Oct 2019 12:00:30 GMT", now)
+	assert.True(ok)
+	assert.Equal(30*time.Second, wait)
+
+	wait, ok = parseRetryAfter("Tue, 01 Oct 2019 11:00:00 GMT", now)
+	assert.True(ok)
+	assert.Equal(time.Duration(0), wait)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(ok)
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	assert := assert.New(t)
+	c := newTestClient(t, Config{BaseURL: "http://localhost", RetryMaxWait: 10 * time.Millisecond})
+
+	for attempt := 0; attempt < 70; attempt++ {
+		wait := c.backoff(attempt)
+		assert.True(wait <= 10*time.Millisecond, "attempt %d waited %s", attempt, wait)
+		assert.True(wait >= 0, "attempt %d waited %s", attempt, wait)
+	}
+}
+
+// flakyServer fails the first `failures` requests with `status`.
+func flakyServer(failures int, status int, header http.Header) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`[{"_id": "1", "username": "bob"}]`))
+	}))
+	return server, &requests
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	server, requests := flakyServer(2, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxRetries: 3})
+	_, found, err := c.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal(3, *requests)
+}
+
+func TestRetryBudgetIsRespected(t *testing.T) {
+	assert := assert.New(t)
+
+	server, requests := flakyServer(5, http.StatusBadGateway, nil)
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxRetries: 2})
+	_, _, err := c.ReadUser(context.Background(), "1")
+	assert.NotNil(err)
+	assert.Equal(3, *requests)
+}
+
+func TestDoesNotRetryNonIdempotentRequestsOnServerError(t *testing.T) {
+	assert := assert.New(t)
+
+	server, requests := flakyServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxRetries: 3})
+	_, err := c.CreateUser(context.Background(), &model.User{Username: "bob"})
+	assert.NotNil(err)
+	assert.Equal(1, *requests)
+}
+
+func TestRetriesNonIdempotentRequestsOnDialError(t *testing.T) {
+	assert := assert.New(t)
+	c := newTestClient(t, Config{BaseURL: "http://localhost", MaxRetries: 3})
+
+	req, err := http.NewRequest("POST", "http://localhost/api/v1/users", nil)
+	if !assert.Nil(err) {
+		return
+	}
+
+	// A request that couldn't connect never reached the Console
+	dialErr := &url.Error{Op: "Post", URL: req.URL.String(), Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+	_, retry := c.retryWait(req, nil, dialErr, 0)
+	assert.True(retry)
+
+	readErr := &url.Error{Op: "Post", URL: req.URL.String(), Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}
+	_, retry = c.retryWait(req, nil, readErr, 0)
+	assert.False(retry, "the Console may have created the user")
+}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	server, requests := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}})
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxRetries: 3})
+	_, err := c.CreateUser(context.Background(), &model.User{Username: "bob"})
+	assert.Nil(err)
+	// The POST is retried once, then the users are listed
+	assert.Equal(3, *requests)
+}
+
+func TestGivesUpWhenRetryAfterExceedsMaxWait(t *testing.T) {
+	assert := assert.New(t)
+
+	server, requests := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}})
+	defer server.Close()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxRetries: 3, RetryMaxWait: time.Second})
+	_, _, err := c.ReadUser(context.Background(), "1")
+	assert.NotNil(err)
+	assert.Equal(1, *requests)
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	assert := assert.New(t)
+
+	server, requests := flakyServer(5, http.StatusServiceUnavailable, http.Header{"Retry-After": {"10"}})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := newTestClient(t, Config{BaseURL: server.URL, MaxRetries: 3})
+	started := time.Now()
+	_, _, err := c.ReadUser(ctx, "1")
+	assert.NotNil(err)
+	assert.Equal(1, *requests)
+	assert.True(time.Since(started) < 5*time.Second, "retry wait should be interrupted")
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+var rolePath = "/rbac/roles"
+
+func (c *Client) ListRoles(ctx context.Context) ([]model.Role, error) {
+	url := c.apiURL() + rolePath
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, newAPIError("list roles", resp)
+	}
+
+	var roles []model.Role
+
+	decoder := json.NewDecoder(resp.Body)
+	if err = decoder.Decode(&roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// ReadRole looks a role up by name in the Console's role list.
+func (c *Client) ReadRole(ctx context.Context, name string) (model.Role, bool, error) {
+	roles, err := c.ListRoles(ctx)
+	if err != nil {
+		return model.Role{}, false, err
+	}
+
+	for _, r := range roles {
+		if r.Name == name {
+			return r, true, nil
+		}
+	}
+	return model.Role{}, false, nil
+}
+
+func (c *Client) CreateRole(ctx context.Context, r *model.Role) (model.Role, error) {
+	return c.sendRole(ctx, "create", "POST", r)
+}
+
+func (c *Client) UpdateRole(ctx context.Context, r *model.Role) (model.Role, error) {
+	return c.sendRole(ctx, "update", "PUT", r)
+}
+
+// sendRole creates or updates a role, the Console POSTs new roles and PUTs
+// changes to existing ones. `operation` is "create" or "update".
+func (c *Client) sendRole(ctx context.Context, operation, method string, r *model.Role) (model.Role, error) {
+	if err := c.checkWritable(operation + " role " + r.Name); err != nil {
+		return model.Role{}, err
+	}
+
+	before := c.auditedRole(ctx, r.Name)
+
+	url := c.apiURL() + rolePath
+	roleJson, err := json.Marshal(r)
+	if err != nil {
+		return model.Role{}, err
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(roleJson))
+	if err != nil {
+		return model.Role{}, err
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		c.audit(ctx, operation, "role", r.Name, before, r, 0, err)
+		return model.Role{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err := newAPIError(operation+" role "+r.Name, resp)
+		c.audit(ctx, operation, "role", r.Name, before, r, resp.StatusCode, err)
+		return model.Role{}, err
+	}
+
+	role, found, err := c.ReadRole(ctx, r.Name)
+	if err == nil && !found {
+		err = fmt.Errorf("Role %s failed, could not fetch after %s", operation, operation)
+	}
+	if err != nil {
+		c.audit(ctx, operation, "role", r.Name, before, r, resp.StatusCode, err)
+		return model.Role{}, err
+	}
+
+	c.audit(ctx, operation, "role", r.Name, before, role, resp.StatusCode, nil)
+	return role, nil
+}
+
+func (c *Client) DeleteRole(ctx context.Context, name string) error {
+	if err := c.checkWritable("delete role " + name); err != nil {
+		return err
+	}
+
+	before := c.auditedRole(ctx, name)
+
+	url := c.apiURL() + rolePath + "/" + name
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		c.audit(ctx, "delete", "role", name, before, before, 0, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err := newAPIError("delete role "+name, resp)
+		c.audit(ctx, "delete", "role", name, before, before, resp.StatusCode, err)
+		return err
+	}
+
+	c.audit(ctx, "delete", "role", name, before, nil, resp.StatusCode, nil)
+	return nil
+}
+
+// auditedRole returns the role named `name` for the audit log's before
+// payload, or nil when changes aren't audited or the role doesn't exist.
+func (c *Client) auditedRole(ctx context.Context, name string) interface{} {
+	if !c.auditing() {
+		return nil
+	}
+	role, found, err := c.ReadRole(ctx, name)
+	if err != nil || !found {
+		return nil
+	}
+	return role
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion converts a TLS version such as "1.2" to its crypto/tls
+// constant. An empty string leaves the choice to crypto/tls.
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	if v, ok := tlsVersions[version]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("Invalid TLS version: %s, must be one of 1.0, 1.1, 1.2 or 1.3", version)
+}
+
+// tlsConfig builds the TLS settings used to talk to the Console.
+func tlsConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.SkipTLSVerify,
+		MinVersion:         config.MinTLSVersion,
+	}
+
+	if config.CACertPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.CACertPEM)) {
+			return nil, fmt.Errorf("Failed to parse CA certificate: no PEM encoded certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (config.ClientCertPEM == "") != (config.ClientKeyPEM == "") {
+		return nil, fmt.Errorf("A client certificate and key must be configured together")
+	}
+	if config.ClientCertPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(config.ClientCertPEM), []byte(config.ClientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func certificatePEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(`[{"_id": "1", "username": "bob"}]`))
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	v, err := ParseTLSVersion("1.2")
+	assert.Nil(err)
+	assert.Equal(uint16(tls.VersionTLS12), v)
+
+	v, err = ParseTLSVersion("")
+	assert.Nil(err)
+	assert.Equal(uint16(0), v)
+
+	_, err = ParseTLSVersion("1.4")
+	assert.NotNil(err)
+}
+
+func TestCustomCACertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(usersHandler))
+	defer server.Close()
+
+	untrusting := newTestClient(t, Config{BaseURL: server.URL})
+	_, _, err := untrusting.ReadUser(context.Background(), "1")
+	assert.NotNil(err, "the test server's certificate should not be trusted by default")
+
+	trusting := newTestClient(t, Config{
+		BaseURL:   server.URL,
+		CACertPEM: certificatePEM(server.Certificate()),
+	})
+	_, found, err := trusting.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.True(found)
+
+	_, err = NewClient(Config{CACertPEM: "not a certificate"})
+	assert.NotNil(err)
+}
+
+func TestClientCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		usersHandler(w, r)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// Reuse the test server's own key pair as the client certificate
+	serverCert := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(serverCert.PrivateKey)
+	assert.Nil(err)
+
+	c := newTestClient(t, Config{
+		BaseURL:       server.URL,
+		CACertPEM:     certificatePEM(server.Certificate()),
+		ClientCertPEM: certificatePEM(server.Certificate()),
+		ClientKeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})),
+	})
+	_, found, err := c.ReadUser(context.Background(), "1")
+	assert.Nil(err)
+	assert.True(found)
+
+	_, err = NewClient(Config{ClientCertPEM: certificatePEM(server.Certificate())})
+	assert.NotNil(err, "a client certificate without a key should be rejected")
+}
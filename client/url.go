@@ -0,0 +1,40 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultAPIPath is the API path every Console serves, it is used until the
+// Console's version is known.
+const defaultAPIPath = "/api/v1"
+
+// apiPathPattern matches Console URLs that already end in an API path such as
+// /api/v1 or /api/v19.11.
+var apiPathPattern = regexp.MustCompile(`/api/v\d+(\.\d+)?$`)
+
+// splitConsoleURL splits the URL of a Console into the Console's root URL and
+// its API path, which is empty when the URL doesn't include one.
+//
+// Self-hosted Consoles are usually configured with their full API URL, e.g.
+// http://localhost:8081/api/v1. Prisma Cloud Compute SaaS Consoles are
+// addressed by a URL that includes a tenant path segment, e.g.
+// https://us-east1.cloud.twistlock.com/us-2-123456789, and serve their API
+// below it.
+func splitConsoleURL(consoleURL string) (string, string, error) {
+	u, err := url.Parse(consoleURL)
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid Console URL %s: %s", consoleURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", "", fmt.Errorf("Invalid Console URL %s: must be an absolute http or https URL", consoleURL)
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	apiPath := apiPathPattern.FindString(path)
+	u.Path = strings.TrimSuffix(path, apiPath)
+
+	return u.String(), apiPath, nil
+}
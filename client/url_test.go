@@ -0,0 +1,29 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitConsoleURL(t *testing.T) {
+	assert := assert.New(t)
+
+	for consoleURL, expected := range map[string][2]string{
+		"http://localhost:8081/api/v1":                         {"http://localhost:8081", "/api/v1"},
+		"http://localhost:8081/api/v1/":                        {"http://localhost:8081", "/api/v1"},
+		"https://console:8083/api/v20.04":                      {"https://console:8083", "/api/v20.04"},
+		"https://console:8083":                                 {"https://console:8083", ""},
+		"https://us-east1.cloud.twistlock.com/us-2-123456789":  {"https://us-east1.cloud.twistlock.com/us-2-123456789", ""},
+		"https://us-east1.cloud.twistlock.com/us-2-123456789/": {"https://us-east1.cloud.twistlock.com/us-2-123456789", ""},
+	} {
+		root, apiPath, err := splitConsoleURL(consoleURL)
+		assert.Nil(err, consoleURL)
+		assert.Equal(expected, [2]string{root, apiPath}, consoleURL)
+	}
+
+	_, _, err := splitConsoleURL("localhost:8081")
+	assert.EqualError(err, "Invalid Console URL localhost:8081: must be an absolute http or https URL")
+	_, _, err = splitConsoleURL("")
+	assert.NotNil(err)
+}
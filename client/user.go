@@ -2,9 +2,9 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
 	"github.com/circleci/terraform-provider-twistlock/model"
@@ -12,48 +12,24 @@ import (
 
 var userPath = "/users"
 
-func userById(id string) func(*model.User) bool {
-	return func(u *model.User) bool {
-		return u.ID == id
-	}
-}
-
-func userByName(username string) func(*model.User) bool {
-	return func(u *model.User) bool {
-		return u.Username == username
-	}
-}
-
-func findUser(f func(*model.User) bool, users []model.User) (model.User, bool) {
-	for i := 0; i < len(users); i++ {
-		if f(&users[i]) {
-			return users[i], true
-		}
-	}
-	return model.User{}, false
-}
-
-func (c *Client) readUsers() ([]model.User, error) {
-	url := c.baseURL + userPath
+func (c *Client) readUsers(ctx context.Context) ([]model.User, error) {
+	url := c.apiURL() + userPath
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.http.Do(req)
+	resp, err := c.do(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Failed to read users: %s", string(body))
+		return nil, newAPIError("read users", resp)
 	}
 
-	users := make([]model.User, 10)
+	var users []model.User
 
 	decoder := json.NewDecoder(resp.Body)
 	if err = decoder.Decode(&users); err != nil {
@@ -63,8 +39,24 @@ func (c *Client) readUsers() ([]model.User, error) {
 	return users, nil
 }
 
-func (c *Client) CreateUser(u *model.User) (model.User, error) {
-	url := c.baseURL + userPath
+func (c *Client) CreateUser(ctx context.Context, u *model.User) (model.User, error) {
+	return c.postUser(ctx, "create", "creation", u)
+}
+
+func (c *Client) UpdateUser(ctx context.Context, u *model.User) (model.User, error) {
+	return c.postUser(ctx, "update", "update", u)
+}
+
+// postUser creates or updates a user, the Console's POST /users does both.
+// `operation` is "create" or "update" and `noun` names it in errors.
+func (c *Client) postUser(ctx context.Context, operation, noun string, u *model.User) (model.User, error) {
+	if err := c.checkWritable(operation + " user " + u.Username); err != nil {
+		return model.User{}, err
+	}
+
+	before := c.auditedUser(ctx, u.Username)
+
+	url := c.apiURL() + userPath
 	userJson, err := json.Marshal(u)
 	if err != nil {
 		return model.User{}, err
@@ -75,68 +67,95 @@ func (c *Client) CreateUser(u *model.User) (model.User, error) {
 		return model.User{}, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.http.Do(req)
+	resp, err := c.do(ctx, req)
+	c.users.forProject(c.project(ctx)).invalidate()
 	if err != nil {
+		c.audit(ctx, operation, "user", u.Username, before, u, 0, err)
 		return model.User{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return model.User{}, fmt.Errorf("Failed to create user %s", u.Username)
+		err := newAPIError(operation+" user "+u.Username, resp)
+		c.audit(ctx, operation, "user", u.Username, before, u, resp.StatusCode, err)
+		return model.User{}, err
 	}
 
-	users, err := c.readUsers()
+	user, found, err := c.ReadUserByName(ctx, u.Username)
+	if err == nil && !found {
+		err = fmt.Errorf("User %s failed, could not fetch after %s", noun, operation)
+	}
 	if err != nil {
+		c.audit(ctx, operation, "user", u.Username, before, u, resp.StatusCode, err)
 		return model.User{}, err
 	}
 
-	if user, found := findUser(userByName(u.Username), users); found {
-		return user, nil
-	}
-
-	return model.User{}, fmt.Errorf("User creation failed, could not fetch after create")
+	c.audit(ctx, operation, "user", u.Username, before, user, resp.StatusCode, nil)
+	return user, nil
 }
 
-func (c *Client) UpdateUser(u *model.User) (model.User, error) {
-	return c.CreateUser(u)
-}
+func (c *Client) DeleteUser(ctx context.Context, u *model.User) error {
+	if err := c.checkWritable("delete user " + u.Username); err != nil {
+		return err
+	}
+
+	before := c.auditedUser(ctx, u.Username)
 
-func (c *Client) DeleteUser(u *model.User) error {
-	url := c.baseURL + userPath + "/" + u.Username
+	url := c.apiURL() + userPath + "/" + u.Username
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.http.Do(req)
+	resp, err := c.do(ctx, req)
+	c.users.forProject(c.project(ctx)).invalidate()
 	if err != nil {
+		c.audit(ctx, "delete", "user", u.Username, before, before, 0, err)
 		return err
 	}
 	defer resp.Body.Close()
 
-	switch resp.StatusCode {
-	case 200:
+	if resp.StatusCode != 200 {
+		err := newAPIError("delete user "+u.Username, resp)
+		c.audit(ctx, "delete", "user", u.Username, before, before, resp.StatusCode, err)
+		return err
+	}
+
+	c.audit(ctx, "delete", "user", u.Username, before, nil, resp.StatusCode, nil)
+	return nil
+}
+
+// auditedUser returns the user named `username` for the audit log's before
+// payload, or nil when changes aren't audited or the user doesn't exist.
+func (c *Client) auditedUser(ctx context.Context, username string) interface{} {
+	if !c.auditing() {
+		return nil
+	}
+	user, found, err := c.ReadUserByName(ctx, username)
+	if err != nil || !found {
 		return nil
-	case 500:
-		return fmt.Errorf("User '%s' does not exist", u.Username)
-	default:
-		return fmt.Errorf("Unknown server-side error deleting %s", u.Username)
 	}
+	return user
 }
 
-func (c *Client) ReadUser(id string) (model.User, bool, error) {
-	users, err := c.readUsers()
+// ReadUser looks a user up by ID in the cached user list.
+func (c *Client) ReadUser(ctx context.Context, id string) (model.User, bool, error) {
+	byID, _, err := c.users.forProject(c.project(ctx)).users(ctx, c.readUsers)
 	if err != nil {
 		return model.User{}, false, err
 	}
 
-	if user, found := findUser(userById(id), users); found {
-		return user, true, nil
+	user, found := byID[id]
+	return user, found, nil
+}
+
+// ReadUserByName looks a user up by username in the cached user list.
+func (c *Client) ReadUserByName(ctx context.Context, username string) (model.User, bool, error) {
+	_, byName, err := c.users.forProject(c.project(ctx)).users(ctx, c.readUsers)
+	if err != nil {
+		return model.User{}, false, err
 	}
 
-	return model.User{}, false, nil
+	user, found := byName[username]
+	return user, found, nil
 }
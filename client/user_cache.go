@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+// userCacheTTL bounds how long a downloaded user list is reused. A Terraform
+// plan or apply refreshes every user within a few seconds of each other, so
+// this only needs to cover a single run.
+var userCacheTTL = time.Minute
+
+// userCache holds the Console's user list indexed by ID and username so that
+// refreshing N users costs one list request rather than N.
+//
+// The lock is held while the list is fetched, so concurrent readers wait for
+// a single download instead of each starting their own.
+type userCache struct {
+	mu      sync.Mutex
+	fetched time.Time
+	byID    map[string]model.User
+	byName  map[string]model.User
+}
+
+// users returns the cached user indexes, calling fetch when the cache is empty
+// or stale.
+func (uc *userCache) users(ctx context.Context, fetch func(context.Context) ([]model.User, error)) (map[string]model.User, map[string]model.User, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if uc.byID != nil && time.Since(uc.fetched) < userCacheTTL {
+		return uc.byID, uc.byName, nil
+	}
+
+	users, err := fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uc.byID = make(map[string]model.User, len(users))
+	uc.byName = make(map[string]model.User, len(users))
+	for _, u := range users {
+		uc.byID[u.ID] = u
+		uc.byName[u.Username] = u
+	}
+	uc.fetched = time.Now()
+
+	return uc.byID, uc.byName, nil
+}
+
+// invalidate discards the cached user list, it must be called after any
+// request that may have changed users.
+func (uc *userCache) invalidate() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.byID = nil
+	uc.byName = nil
+}
+
+// userCaches holds a userCache per project, since each project has its own
+// users.
+type userCaches struct {
+	mu        sync.Mutex
+	byProject map[string]*userCache
+}
+
+// forProject returns the cache for `project`, creating it if needed.
+func (ucs *userCaches) forProject(project string) *userCache {
+	ucs.mu.Lock()
+	defer ucs.mu.Unlock()
+
+	if ucs.byProject == nil {
+		ucs.byProject = map[string]*userCache{}
+	}
+	uc, ok := ucs.byProject[project]
+	if !ok {
+		uc = &userCache{}
+		ucs.byProject[project] = uc
+	}
+	return uc
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestUserCache(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := fakeconsole.New("admin", "admin-password")
+	defer console.Close()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		console.AddUser(model.User{Username: name, Role: model.RoleUser, AuthType: model.AuthTypeBasic}, "password")
+	}
+
+	c := newTestClient(t, Config{Username: "admin", Password: "admin-password", BaseURL: console.URL()})
+
+	// Concurrent refreshes share a single list request
+	var wg sync.WaitGroup
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			_, found, err := c.ReadUser(ctx, name)
+			assert.Nil(err)
+			assert.Equal(name != "dave", found)
+		}(name)
+	}
+	wg.Wait()
+	_, found, err := c.ReadUserByName(ctx, "alice")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal(1, console.Requests("GET", "/users"))
+
+	// Writes invalidate the cache
+	_, err = c.CreateUser(ctx, &model.User{Username: "dave", Password: "password", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	_, found, err = c.ReadUser(ctx, "dave")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal(2, console.Requests("GET", "/users"))
+
+	assert.Nil(c.DeleteUser(ctx, &model.User{Username: "bob"}))
+	_, found, err = c.ReadUser(ctx, "bob")
+	assert.Nil(err)
+	assert.False(found)
+	assert.Equal(3, console.Requests("GET", "/users"))
+}
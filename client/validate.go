@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+const pingPath = "/_ping"
+
+// identity is what Validate learnt about the Console's user.
+type identity struct {
+	// role is empty when the Console refused to list users
+	role model.UserRole
+	// listUsers is false when the Console refused to list users, which only
+	// admins may do
+	listUsers bool
+	// customRole holds the permissions of a custom role, it is nil for
+	// built-in roles and when the Console refused to list roles
+	customRole *model.Role
+}
+
+// can reports whether the user may have permission p. The permissions of
+// custom roles the Console didn't list are unknown, so they are assumed.
+func (id *identity) can(p model.Permission) bool {
+	switch {
+	case id.customRole != nil:
+		return id.customRole.Can(p)
+	case id.role.BuiltIn():
+		return id.role.Can(p)
+	default:
+		return true
+	}
+}
+
+// Validate checks that the Console is reachable and accepts the client's
+// credentials, and looks up the user's role so that Authorize can refuse
+// operations the role doesn't allow.
+func (c *Client) Validate(ctx context.Context) error {
+	if err := c.ping(ctx); err != nil {
+		return err
+	}
+
+	if c.authMethod == AuthMethodToken {
+		if _, err := c.bearerToken(ctx); err != nil {
+			return c.credentialsError(err)
+		}
+	}
+
+	username := c.user()
+	u, found, err := c.ReadUserByName(ctx, username)
+	switch {
+	case IsUnauthorized(err):
+		return c.credentialsError(err)
+	case IsForbidden(err):
+		c.identity = &identity{}
+	case err != nil:
+		return err
+	case found:
+		c.identity = &identity{role: u.Role, listUsers: true}
+		if !u.Role.BuiltIn() {
+			if role, found, err := c.ReadRole(ctx, string(u.Role)); err == nil && found {
+				c.identity.customRole = &role
+			}
+		}
+	default:
+		// Access keys and users from identity providers aren't necessarily
+		// listed
+		log.Printf("[WARN] User %s is not listed by the Twistlock Console, skipping permission checks", username)
+	}
+
+	return nil
+}
+
+// credentialsError explains that the Console rejected the credentials when
+// err is a 401.
+func (c *Client) credentialsError(err error) error {
+	if !IsUnauthorized(err) {
+		return err
+	}
+	return fmt.Errorf("The Twistlock Console rejected the credentials for %s, check the provider's username and password or access key: %s", c.user(), err)
+}
+
+// ping checks that the Console answers its unauthenticated health check.
+func (c *Client) ping(ctx context.Context) error {
+	url := c.apiURL() + pingPath
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.withFailover(req.WithContext(ctx), true, c.http.Do)
+	if err != nil {
+		return fmt.Errorf("Could not reach the Twistlock Console at %s: %s", c.consoleURL(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return newAPIError("ping the Twistlock Console at "+c.consoleURL(), resp)
+	}
+
+	return nil
+}
+
+// Authorize returns a model.PermissionError when Validate found that the
+// client's user doesn't have permission `p`. Without Validate every
+// permission is assumed.
+func (c *Client) Authorize(p model.Permission) error {
+	id := c.identity
+	switch {
+	case id == nil:
+		return nil
+	case id.role != "":
+		if id.can(p) {
+			return nil
+		}
+		return &model.PermissionError{Username: c.user(), Role: id.role, Permission: p}
+	case !id.listUsers && p == model.PermissionManageUsers:
+		// A user whose role is unknown because it may not list users can't
+		// manage them either
+		return &model.PermissionError{Username: c.user(), Permission: p}
+	default:
+		return nil
+	}
+}
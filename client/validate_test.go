@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestValidateLearnsRole(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("admin", "admin-password")
+	defer console.Close()
+	console.AddUser(model.User{Username: "ci_bot", Role: model.RoleCI, AuthType: model.AuthTypeBasic}, "secret")
+
+	c := newTestClient(t, Config{Username: "ci_bot", Password: "secret", BaseURL: console.URL()})
+	assert.Nil(c.Authorize(model.PermissionManageUsers), "permissions are assumed before validation")
+
+	assert.Nil(c.Validate(context.Background()))
+	assert.EqualError(c.Authorize(model.PermissionManageUsers), "user ci_bot has role ci, which cannot manage users")
+	assert.EqualError(c.Authorize(model.PermissionManageCVEPolicy), "user ci_bot has role ci, which cannot manage the CVE policy")
+}
+
+func TestValidateForbiddenUserList(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("operator", "secret")
+	defer console.Close()
+	console.InjectFault(&fakeconsole.Fault{Method: "GET", Path: "/users", StatusCode: http.StatusForbidden, Body: `{"err": "forbidden"}`})
+
+	c := newTestClient(t, Config{Username: "operator", Password: "secret", BaseURL: console.URL()})
+	assert.Nil(c.Validate(context.Background()))
+	assert.EqualError(c.Authorize(model.PermissionManageUsers), "user operator cannot manage users")
+	assert.Nil(c.Authorize(model.PermissionManageCVEPolicy))
+}
+
+func TestValidateUnlistedUser(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("access-key-id", "secret")
+	defer console.Close()
+	console.InjectFault(&fakeconsole.Fault{Method: "GET", Path: "/users", StatusCode: http.StatusOK, Body: `[]`})
+
+	c := newTestClient(t, Config{AccessKey: "access-key-id", SecretKey: "secret", BaseURL: console.URL()})
+	assert.Nil(c.Validate(context.Background()))
+	assert.Nil(c.Authorize(model.PermissionManageUsers))
+}
+
+func TestValidateErrors(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := fakeconsole.New("admin", "secret")
+	c := newTestClient(t, Config{Username: "admin", Password: "wrong", BaseURL: console.URL()})
+	assert.EqualError(c.Validate(ctx), "The Twistlock Console rejected the credentials for admin, check the provider's username and password or access key: "+
+		"Failed to read users: GET /api/v1/users returned 401 Unauthorized: unauthorized")
+
+	console.Close()
+	err := c.Validate(ctx)
+	assert.NotNil(err)
+	assert.True(strings.HasPrefix(err.Error(), "Could not reach the Twistlock Console at "+console.ConsoleURL()+": "), err.Error())
+}
+
+func TestValidateLearnsCustomRole(t *testing.T) {
+	assert := assert.New(t)
+
+	console := fakeconsole.New("admin", "admin-password")
+	defer console.Close()
+	console.AddRole(model.Role{Name: "policy-editor", Permissions: []model.RolePermission{{Name: model.RoleAreaPolicies, ReadWrite: true}}})
+	console.AddUser(model.User{Username: "policy_bot", Role: "policy-editor", AuthType: model.AuthTypeBasic}, "secret")
+
+	c := newTestClient(t, Config{Username: "policy_bot", Password: "secret", BaseURL: console.URL()})
+	assert.Nil(c.Validate(context.Background()))
+	assert.Nil(c.Authorize(model.PermissionManageCVEPolicy))
+	assert.EqualError(c.Authorize(model.PermissionManageUsers), "user policy_bot has role policy-editor, which cannot manage users")
+}
+
+func TestValidateUsesDetectedAPIPath(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := fakeconsole.New("admin", "secret")
+	defer console.Close()
+	console.SetVersion("22.06.179")
+
+	c := newTestClient(t, Config{Username: "admin", Password: "secret", BaseURL: console.ConsoleURL()})
+	_, err := c.DetectVersion(ctx)
+	assert.Nil(err)
+
+	console.InjectFault(&fakeconsole.Fault{Method: "GET", Path: "/users", StatusCode: http.StatusUnauthorized, Body: `{"err": "unauthorized"}`})
+	assert.EqualError(c.Validate(ctx), "The Twistlock Console rejected the credentials for admin, check the provider's username and password or access key: "+
+		"Failed to read users: GET /api/v22.06/users returned 401 Unauthorized: unauthorized")
+}
+
+func TestAuthorize(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newTestClient(t, Config{Username: "bob", Password: "secret", BaseURL: "http://localhost/api/v1"})
+	for _, test := range []struct {
+		identity   *identity
+		permission model.Permission
+		denied     string
+	}{
+		{nil, model.PermissionManageUsers, ""},
+		{&identity{role: model.RoleAdmin, listUsers: true}, model.PermissionManageUsers, ""},
+		{&identity{role: model.RoleCI, listUsers: true}, model.PermissionManageCVEPolicy, "user bob has role ci, which cannot manage the CVE policy"},
+		{&identity{}, model.PermissionManageUsers, "user bob cannot manage users"},
+		{&identity{}, model.PermissionManageCVEPolicy, ""},
+	} {
+		c.identity = test.identity
+		err := c.Authorize(test.permission)
+		if test.denied == "" {
+			assert.Nil(err)
+			continue
+		}
+		if assert.IsType(&model.PermissionError{}, err) {
+			assert.EqualError(err, test.denied)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+const versionPath = "/version"
+
+// Consoles serve their API under /api/v<major>.<minor> since 20.04, older
+// releases only serve /api/v1.
+const (
+	versionedAPIMajor = 20
+	versionedAPIMinor = 4
+)
+
+// DetectVersion queries the Console's release and, unless the configured URL
+// included an API path, switches the client to the API path matching it. It
+// must be called before the client is shared between goroutines.
+func (c *Client) DetectVersion(ctx context.Context) (model.Version, error) {
+	url := c.consoleURL() + defaultAPIPath + versionPath
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return model.Version{}, err
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return model.Version{}, c.credentialsError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return model.Version{}, c.credentialsError(newAPIError("read Console version", resp))
+	}
+
+	var release string
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return model.Version{}, fmt.Errorf("Failed to read Console version: %s", err)
+	}
+	version, err := model.ParseVersion(release)
+	if err != nil {
+		return model.Version{}, err
+	}
+
+	c.version = version
+	if !c.pinnedAPI && version.AtLeast(versionedAPIMajor, versionedAPIMinor) {
+		c.apiPath = fmt.Sprintf("/api/v%d.%02d", version.Major, version.Minor)
+	}
+	log.Printf("[INFO] Twistlock Console %s detected, using API %s", version, c.apiURL())
+
+	return version, nil
+}
+
+// Version returns the Console release found by DetectVersion, or the zero
+// Version if it wasn't called.
+func (c *Client) Version() model.Version {
+	return c.version
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func TestDetectVersionPicksAPIPath(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	for release, apiPath := range map[string]string{
+		"19.11.512": "/api/v1",
+		"20.04.169": "/api/v20.04",
+		"22.06.179": "/api/v22.06",
+	} {
+		console := fakeconsole.New("admin", "secret")
+		console.SetVersion(release)
+
+		c := newTestClient(t, Config{Username: "admin", Password: "secret", BaseURL: console.ConsoleURL()})
+		version, err := c.DetectVersion(ctx)
+		assert.Nil(err, release)
+		assert.Equal(release, version.String())
+		assert.Equal(version, c.Version())
+		assert.Equal(console.ConsoleURL()+apiPath, c.apiURL(), release)
+
+		// An API path in the configured URL is kept
+		pinned := newTestClient(t, Config{Username: "admin", Password: "secret", BaseURL: console.URL()})
+		_, err = pinned.DetectVersion(ctx)
+		assert.Nil(err, release)
+		assert.Equal(console.URL(), pinned.apiURL(), release)
+
+		console.Close()
+	}
+}
+
+func TestDetectVersionErrors(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := fakeconsole.New("admin", "secret")
+	defer console.Close()
+	console.InjectFault(&fakeconsole.Fault{Method: "GET", Path: "/version", StatusCode: http.StatusOK, Body: `"unreleased"`})
+	c := newTestClient(t, Config{Username: "admin", Password: "secret", BaseURL: console.ConsoleURL()})
+	_, err := c.DetectVersion(ctx)
+	assert.EqualError(err, `Invalid Console version: "unreleased"`)
+	assert.Equal(model.Version{}, c.Version())
+
+	c = newTestClient(t, Config{Username: "admin", Password: "secret", BaseURL: console.ConsoleURL() + "/tenant"})
+	_, err = c.DetectVersion(ctx)
+	assert.EqualError(err, "Failed to read Console version: GET /tenant/api/v1/version returned 404 Not Found: not found")
+}
@@ -0,0 +1,596 @@
+// Package fakeconsole is an in-memory stand-in for the Twistlock Console API,
+// used to test the client and the Terraform resources without a real Console.
+//
+// It implements the /_ping, /authenticate, /version, /users, /rbac/roles and
+// /policies/cve endpoints under /api/v1, and under /api/v<major>.<minor> when its version is
+// 20.04 or later, scoped to Twistlock projects with the `project` query
+// parameter. It supports injecting faults such as server errors, latency and
+// malformed responses.
+package fakeconsole
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+// APIPrefix is the path under which the fake console serves its API.
+const APIPrefix = "/api/v1"
+
+// DefaultVersion is the release the fake console reports unless changed with
+// SetVersion.
+const DefaultVersion = "19.11.512"
+
+// TokenLifetime is how long tokens issued by /authenticate are valid for.
+const TokenLifetime = 30 * time.Minute
+
+// Console is a running fake Twistlock Console.
+type Console struct {
+	Username string
+	Password string
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	now      func() time.Time
+	version  model.Version
+	projects map[string]*project
+	tokens   map[string]time.Time
+	issued   int
+	faults   []*Fault
+	requests map[string]int
+}
+
+// project holds the objects scoped to a Twistlock project. The master project
+// is named "".
+type project struct {
+	users     map[string]model.User
+	passwords map[string]string
+	// roles are the custom roles, the built-in ones are implied
+	roles     map[string]model.Role
+	cvePolicy model.CVEPolicy
+}
+
+func newProject() *project {
+	return &project{
+		users:     map[string]model.User{},
+		passwords: map[string]string{},
+		roles:     map[string]model.Role{},
+		cvePolicy: model.CVEPolicy{PolicyType: "cve", ID: "cve", Rules: []model.CVEPolicyRule{}},
+	}
+}
+
+// New starts a fake console which accepts `username` and `password`, either
+// directly or exchanged for a token. The configured user exists as an admin
+// in the master project.
+func New(username, password string) *Console {
+	c := &Console{
+		Username: username,
+		Password: password,
+		now:      time.Now,
+		version:  mustParseVersion(DefaultVersion),
+		projects: map[string]*project{"": newProject()},
+		tokens:   map[string]time.Time{},
+		requests: map[string]int{},
+	}
+	c.putUser(c.projects[""], model.User{Username: username, Role: model.RoleAdmin, AuthType: model.AuthTypeBasic}, password)
+
+	c.server = httptest.NewServer(http.HandlerFunc(c.serveHTTP))
+	return c
+}
+
+// URL is the base URL of the fake console's /api/v1 API, suitable for the
+// provider's base_url.
+func (c *Console) URL() string {
+	return c.server.URL + APIPrefix
+}
+
+// ConsoleURL is the fake console's URL without an API path, which leaves
+// picking the API path to the client.
+func (c *Console) ConsoleURL() string {
+	return c.server.URL
+}
+
+// Close shuts the fake console down.
+func (c *Console) Close() {
+	c.server.Close()
+}
+
+// SetClock replaces the clock used for lastModified timestamps and token
+// expiry.
+func (c *Console) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// SetVersion changes the release reported by /version, e.g. "20.04.169".
+func (c *Console) SetVersion(version string) {
+	v := mustParseVersion(version)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version = v
+}
+
+func mustParseVersion(version string) model.Version {
+	v, err := model.ParseVersion(version)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// AddProject creates an empty Twistlock project. Requests for projects that
+// don't exist are rejected.
+func (c *Console) AddProject(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.projects[name]; !exists {
+		c.projects[name] = newProject()
+	}
+}
+
+// AddUser creates or replaces a user in the master project directly,
+// bypassing the API.
+func (c *Console) AddUser(u model.User, password string) model.User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.putUser(c.projects[""], u, password)
+}
+
+// User returns the user called `username` in the master project, if it
+// exists.
+func (c *Console) User(username string) (model.User, bool) {
+	return c.ProjectUser("", username)
+}
+
+// ProjectUser returns the user called `username` in a project, if it exists.
+func (c *Console) ProjectUser(projectName, username string) (model.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.projects[projectName]
+	if !ok {
+		return model.User{}, false
+	}
+	u, ok := p.users[username]
+	return u, ok
+}
+
+// RemoveUser deletes a user from the master project directly, bypassing the
+// API.
+func (c *Console) RemoveUser(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.projects[""].users, username)
+	delete(c.projects[""].passwords, username)
+}
+
+// UserPassword returns the password last set for `username` in the master
+// project.
+func (c *Console) UserPassword(username string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.projects[""].passwords[username]
+}
+
+// AddRole creates or replaces a custom role in the master project directly,
+// bypassing the API.
+func (c *Console) AddRole(r model.Role) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.projects[""].roles[r.Name] = r
+}
+
+// Role returns the custom role called `name` in the master project, if it
+// exists.
+func (c *Console) Role(name string) (model.Role, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.projects[""].roles[name]
+	return r, ok
+}
+
+// CVEPolicy returns the master project's CVE policy.
+func (c *Console) CVEPolicy() model.CVEPolicy {
+	return c.ProjectCVEPolicy("")
+}
+
+// ProjectCVEPolicy returns a project's CVE policy.
+func (c *Console) ProjectCVEPolicy(projectName string) model.CVEPolicy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.projects[projectName]
+	if !ok {
+		return model.CVEPolicy{}
+	}
+	return p.cvePolicy
+}
+
+// RevokeTokens invalidates every token issued so far.
+func (c *Console) RevokeTokens() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = map[string]time.Time{}
+}
+
+// Requests returns how many requests were received for `method` and `path`,
+// where path is relative to the API path, e.g. "GET", "/users".
+func (c *Console) Requests(method, path string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[method+" "+path]
+}
+
+// ResetRequests zeroes the request counters.
+func (c *Console) ResetRequests() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = map[string]int{}
+}
+
+// putUser stores a user in project `p`, c.mu must be held.
+func (c *Console) putUser(p *project, u model.User, password string) model.User {
+	u.ID = u.Username
+	u.Password = ""
+	u.LastModified = c.now().UTC()
+	p.users[u.Username] = u
+	if password != "" {
+		p.passwords[u.Username] = password
+	}
+	return u
+}
+
+func (c *Console) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	path, ok := c.apiPath(r.URL.Path)
+	if !ok {
+		c.mu.Unlock()
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	c.requests[r.Method+" "+path]++
+	fault := c.matchFault(r.Method, path)
+	c.mu.Unlock()
+
+	if fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode != 0 {
+			w.WriteHeader(fault.StatusCode)
+			w.Write([]byte(fault.Body))
+			return
+		}
+	}
+
+	switch path {
+	case "/_ping":
+		return
+	case "/authenticate":
+		c.handleAuthenticate(w, r)
+		return
+	}
+
+	if !c.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	projectName := r.URL.Query().Get("project")
+	c.mu.Lock()
+	p, ok := c.projects[projectName]
+	version := c.version
+	c.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("project %s does not exist", projectName))
+		return
+	}
+
+	switch {
+	case path == "/version":
+		writeJSON(w, version.String())
+	case path == "/users":
+		c.handleUsers(w, r, p)
+	case strings.HasPrefix(path, "/users/"):
+		c.handleUser(w, r, p, strings.TrimPrefix(path, "/users/"))
+	case strings.HasPrefix(path, "/rbac/roles") && !version.AtLeast(model.CustomRolesMajor, model.CustomRolesMinor):
+		// Consoles without custom roles don't serve the roles API
+		writeError(w, http.StatusNotFound, "not found")
+	case path == "/rbac/roles":
+		c.handleRoles(w, r, p)
+	case strings.HasPrefix(path, "/rbac/roles/"):
+		c.handleRole(w, r, p, strings.TrimPrefix(path, "/rbac/roles/"))
+	case path == "/policies/cve":
+		c.handleCVEPolicy(w, r, p)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// apiPath strips the API path from a request path, reporting whether the
+// request was for an API path the console serves. c.mu must be held.
+func (c *Console) apiPath(path string) (string, bool) {
+	prefixes := []string{APIPrefix}
+	if c.version.AtLeast(20, 4) {
+		prefixes = append(prefixes, fmt.Sprintf("/api/v%d.%02d", c.version.Major, c.version.Minor))
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix+"/") {
+			return strings.TrimPrefix(path, prefix), true
+		}
+	}
+	return "", false
+}
+
+func (c *Console) authorized(r *http.Request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if username, password, ok := r.BasicAuth(); ok {
+		stored, exists := c.projects[""].passwords[username]
+		return exists && stored == password
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	expiry, ok := c.tokens[strings.TrimPrefix(auth, "Bearer ")]
+	return ok && c.now().Before(expiry)
+}
+
+func (c *Console) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	credentials := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, exists := c.projects[""].passwords[credentials.Username]
+	if !exists || stored != credentials.Password {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	c.issued++
+	expiry := c.now().Add(TokenLifetime)
+	token := newToken(c.issued, expiry)
+	c.tokens[token] = expiry
+
+	writeJSON(w, map[string]string{"token": token})
+}
+
+// newToken builds an unsigned JWT carrying an `exp` claim.
+func newToken(serial int, expiry time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d,"jti":"%d"}`, expiry.Unix(), serial)))
+	return header + "." + claims + ".fake"
+}
+
+func (c *Console) handleUsers(w http.ResponseWriter, r *http.Request, p *project) {
+	switch r.Method {
+	case "GET":
+		c.mu.Lock()
+		users := make([]model.User, 0, len(p.users))
+		for _, u := range p.users {
+			users = append(users, u)
+		}
+		c.mu.Unlock()
+		writeJSON(w, users)
+	case "POST":
+		req := userRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		u, err := req.user()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.mu.Lock()
+		if _, custom := p.roles[string(u.Role)]; !custom && !u.Role.BuiltIn() {
+			c.mu.Unlock()
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid role %s", u.Role))
+			return
+		}
+		_, exists := p.users[u.Username]
+		if !exists && u.AuthType == model.AuthTypeBasic && u.Password == "" {
+			c.mu.Unlock()
+			writeError(w, http.StatusBadRequest, "password is required for basic users")
+			return
+		}
+		c.putUser(p, u, u.Password)
+		c.mu.Unlock()
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// userRequest is the body of a POST to /users. Its enums are plain strings so
+// that invalid values can be reported like the Console does.
+type userRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	AuthType string `json:"authType"`
+}
+
+func (r userRequest) user() (model.User, error) {
+	if r.Username == "" {
+		return model.User{}, fmt.Errorf("username is required")
+	}
+	var role model.UserRole
+	if err := role.UnmarshalText([]byte(r.Role)); err != nil {
+		return model.User{}, fmt.Errorf("invalid role %s", r.Role)
+	}
+	var auth model.UserAuthType
+	if err := auth.UnmarshalText([]byte(r.AuthType)); err != nil {
+		return model.User{}, fmt.Errorf("invalid authType %s", r.AuthType)
+	}
+	return model.User{
+		Username: r.Username,
+		Password: r.Password,
+		Role:     role,
+		AuthType: auth,
+	}, nil
+}
+
+func (c *Console) handleUser(w http.ResponseWriter, r *http.Request, p *project, username string) {
+	if r.Method != "DELETE" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Like the real Console, deleting a user that doesn't exist is a server
+	// error rather than a 404
+	if _, exists := p.users[username]; !exists {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("user %s does not exist", username))
+		return
+	}
+	delete(p.users, username)
+	delete(p.passwords, username)
+}
+
+func (c *Console) handleRoles(w http.ResponseWriter, r *http.Request, p *project) {
+	if r.Method == "GET" {
+		c.mu.Lock()
+		roles := make([]model.Role, 0, len(model.UserRoles)+len(p.roles))
+		for _, name := range model.UserRoles {
+			roles = append(roles, model.Role{Name: string(name), System: true, Permissions: []model.RolePermission{}})
+		}
+		for _, role := range p.roles {
+			roles = append(roles, role)
+		}
+		c.mu.Unlock()
+		writeJSON(w, roles)
+		return
+	}
+	if r.Method != "POST" && r.Method != "PUT" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	role := model.Role{}
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if role.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if role.Permissions == nil {
+		role.Permissions = []model.RolePermission{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, exists := p.roles[role.Name]
+	switch {
+	case model.UserRole(role.Name).BuiltIn():
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("role %s is a system role", role.Name))
+	case r.Method == "POST" && exists:
+		writeError(w, http.StatusConflict, fmt.Sprintf("role %s already exists", role.Name))
+	case r.Method == "PUT" && !exists:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("role %s does not exist", role.Name))
+	default:
+		role.System = false
+		p.roles[role.Name] = role
+	}
+}
+
+func (c *Console) handleRole(w http.ResponseWriter, r *http.Request, p *project, name string) {
+	if r.Method != "DELETE" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := p.roles[name]; !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("role %s does not exist", name))
+		return
+	}
+	for _, u := range p.users {
+		if string(u.Role) == name {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("role %s is assigned to user %s", name, u.Username))
+			return
+		}
+	}
+	delete(p.roles, name)
+}
+
+func (c *Console) handleCVEPolicy(w http.ResponseWriter, r *http.Request, p *project) {
+	switch r.Method {
+	case "GET":
+		c.mu.Lock()
+		policy := p.cvePolicy
+		c.mu.Unlock()
+		writeJSON(w, policy)
+	case "PUT":
+		policy := model.CVEPolicy{}
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			writeError(w, http.StatusBadRequest, "malformed request body")
+			return
+		}
+		if policy.ID != "cve" || policy.PolicyType != "cve" {
+			writeError(w, http.StatusBadRequest, "policy ID and type must be cve")
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		now := c.now().UTC()
+		if policy.Rules == nil {
+			policy.Rules = []model.CVEPolicyRule{}
+		}
+		for i := range policy.Rules {
+			policy.Rules[i].Modified = now
+			policy.Rules[i].PreviousName = ""
+		}
+		p.cvePolicy = policy
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"err": message})
+}
@@ -0,0 +1,173 @@
+package fakeconsole
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func newClient(t *testing.T, console *Console, authMethod client.AuthMethod) *client.Client {
+	c, err := client.NewClient(client.Config{
+		Username:   console.Username,
+		Password:   console.Password,
+		BaseURL:    console.URL(),
+		AuthMethod: authMethod,
+	})
+	if err != nil {
+		t.Fatalf("Could not create client: %s", err)
+	}
+	return c
+}
+
+func TestUserLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := New("admin", "admin-password")
+	defer console.Close()
+	created := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	console.SetClock(func() time.Time { return created })
+
+	c := newClient(t, console, client.AuthMethodBasic)
+
+	user, err := c.CreateUser(ctx, &model.User{Username: "bob", Password: "hunter2", Role: model.RoleUser, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	assert.Equal(model.User{ID: "bob", Username: "bob", Role: model.RoleUser, AuthType: model.AuthTypeBasic, LastModified: created}, user)
+	assert.Equal("hunter2", console.UserPassword("bob"))
+
+	// Updating without a password keeps the existing one
+	_, err = c.UpdateUser(ctx, &model.User{Username: "bob", Role: model.RoleAuditor, AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	assert.Equal("hunter2", console.UserPassword("bob"))
+
+	_, err = c.CreateUser(ctx, &model.User{Username: "eve", Role: "admn", AuthType: model.AuthTypeBasic, Password: "x"})
+	assert.EqualError(err, "Failed to create user eve: POST /api/v1/users returned 400 Bad Request: invalid role admn")
+
+	assert.Nil(c.DeleteUser(ctx, &model.User{Username: "bob"}))
+	assert.EqualError(c.DeleteUser(ctx, &model.User{Username: "bob"}), "Failed to delete user bob: DELETE /api/v1/users/bob returned 500 Internal Server Error: user bob does not exist")
+}
+
+func TestTokenExpiry(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := New("admin", "admin-password")
+	defer console.Close()
+
+	c := newClient(t, console, client.AuthMethodToken)
+
+	_, err := c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	assert.Equal(1, console.Requests("POST", "/authenticate"))
+
+	console.RevokeTokens()
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	assert.Equal(2, console.Requests("POST", "/authenticate"))
+}
+
+func TestFaults(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := New("admin", "admin-password")
+	defer console.Close()
+
+	c := newClient(t, console, client.AuthMethodBasic)
+
+	console.InjectFault(&Fault{Method: "GET", Path: "/users", StatusCode: http.StatusServiceUnavailable, Times: 1})
+	_, _, err := c.ReadUser(ctx, "admin")
+	assert.NotNil(err)
+	_, _, err = c.ReadUser(ctx, "admin")
+	assert.Nil(err, "the fault should only apply once")
+
+	console.InjectFault(MalformedJSON("GET", "/policies/cve"))
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.NotNil(err)
+	console.ClearFaults()
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+
+	console.InjectFault(&Fault{Latency: time.Second})
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = c.ReadCVEPolicy(timeout)
+	assert.NotNil(err)
+}
+
+func TestVersionedAPI(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := New("admin", "admin-password")
+	defer console.Close()
+	console.SetVersion("22.06.179")
+
+	c, err := client.NewClient(client.Config{
+		Username: console.Username,
+		Password: console.Password,
+		BaseURL:  console.ConsoleURL(),
+	})
+	assert.Nil(err)
+
+	version, err := c.DetectVersion(ctx)
+	assert.Nil(err)
+	assert.Equal(model.Version{Major: 22, Minor: 6, Build: 179}, version)
+
+	_, err = c.ReadCVEPolicy(ctx)
+	assert.Nil(err)
+	assert.Equal(1, console.Requests("GET", "/policies/cve"))
+}
+
+func TestRoleLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	console := New("admin", "admin-password")
+	defer console.Close()
+	c := newClient(t, console, client.AuthMethodBasic)
+
+	role, err := c.CreateRole(ctx, &model.Role{
+		Name:        "policy-editor",
+		Description: "Edits policies",
+		Permissions: []model.RolePermission{{Name: model.RoleAreaPolicies, ReadWrite: true}},
+	})
+	assert.Nil(err)
+	assert.Equal(model.Role{
+		Name:        "policy-editor",
+		Description: "Edits policies",
+		Permissions: []model.RolePermission{{Name: model.RoleAreaPolicies, ReadWrite: true}},
+	}, role)
+
+	_, err = c.CreateRole(ctx, &model.Role{Name: "policy-editor"})
+	assert.True(client.IsConflict(err))
+
+	roles, err := c.ListRoles(ctx)
+	assert.Nil(err)
+	assert.Len(roles, len(model.UserRoles)+1)
+
+	// Users may have custom roles
+	_, err = c.CreateUser(ctx, &model.User{Username: "bob", Password: "hunter2", Role: "policy-editor", AuthType: model.AuthTypeBasic})
+	assert.Nil(err)
+	assert.EqualError(c.DeleteRole(ctx, "policy-editor"), "Failed to delete role policy-editor: DELETE /api/v1/rbac/roles/policy-editor returned 400 Bad Request: role policy-editor is assigned to user bob")
+	assert.Nil(c.DeleteUser(ctx, &model.User{Username: "bob"}))
+
+	_, err = c.UpdateRole(ctx, &model.Role{Name: "policy-editor"})
+	assert.Nil(err)
+	role, _ = console.Role("policy-editor")
+	assert.Empty(role.Permissions)
+
+	_, err = c.UpdateRole(ctx, &model.Role{Name: "admin"})
+	assert.EqualError(err, "Failed to update role admin: PUT /api/v1/rbac/roles returned 400 Bad Request: role admin is a system role")
+
+	assert.Nil(c.DeleteRole(ctx, "policy-editor"))
+	assert.True(client.IsNotFound(c.DeleteRole(ctx, "policy-editor")))
+}
@@ -0,0 +1,72 @@
+package fakeconsole
+
+import (
+	"net/http"
+	"time"
+)
+
+// Fault changes how the fake console answers matching requests.
+type Fault struct {
+	// Method matches the request method, empty matches any method
+	Method string
+	// Path matches the request path relative to APIPrefix, empty matches any
+	// path
+	Path string
+	// Latency delays the response
+	Latency time.Duration
+	// StatusCode, when set, is returned along with Body instead of the normal
+	// response
+	StatusCode int
+	Body       string
+	// Times limits how many requests the fault applies to, zero means every
+	// request until the fault is cleared
+	Times int
+}
+
+// ServerError fails matching requests with a 500.
+func ServerError(method, path string) *Fault {
+	return &Fault{Method: method, Path: path, StatusCode: http.StatusInternalServerError, Body: `{"err":"internal server error"}`}
+}
+
+// MalformedJSON answers matching requests with a 200 and a body that is not
+// valid JSON.
+func MalformedJSON(method, path string) *Fault {
+	return &Fault{Method: method, Path: path, StatusCode: http.StatusOK, Body: `{"not": json`}
+}
+
+// InjectFault adds a fault. Faults are matched in the order they were
+// injected.
+func (c *Console) InjectFault(f *Fault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = append(c.faults, f)
+}
+
+// ClearFaults removes all injected faults.
+func (c *Console) ClearFaults() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = nil
+}
+
+// matchFault returns the first fault matching the request and consumes one of
+// its uses, c.mu must be held.
+func (c *Console) matchFault(method, path string) *Fault {
+	for i, f := range c.faults {
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if f.Path != "" && f.Path != path {
+			continue
+		}
+
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				c.faults = append(c.faults[:i], c.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
@@ -7,4 +7,5 @@ require (
 	github.com/hashicorp/terraform v0.12.0
 	github.com/hashicorp/vault v0.10.4
 	github.com/stretchr/testify v1.3.0
+	golang.org/x/crypto v0.0.0-20190426145343-a29dc8fdc734
 )
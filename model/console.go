@@ -0,0 +1,19 @@
+package model
+
+// Console is every Twistlock Console operation the Terraform provider uses.
+//
+// client.Client implements Console. Other implementations can wrap a client
+// to add behaviour such as caching or auditing.
+type Console interface {
+	UserService
+	CVEPolicyService
+	RoleService
+
+	// Version is the Console's release, resources can use it to pick payload
+	// shapes or to refuse features the Console doesn't have
+	Version() Version
+
+	// Authorize returns a PermissionError when the Console's user is known
+	// not to have permission `p`, and nil when it has it or isn't known
+	Authorize(p Permission) error
+}
@@ -1,16 +1,17 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 )
 
+// CVEPolicyService manages the CVE policy on a Twistlock Console. There is
+// exactly one CVE policy, it cannot be created or deleted, only replaced.
 type CVEPolicyService interface {
-	CreateCVEPolicy(p *CVEPolicy) (CVEPolicy, error)
-	UpdateCVEPolicy(p *CVEPolicy) (CVEPolicy, error)
-	DeleteCVEPolicy(p *CVEPolicy) (CVEPolicy, error)
-	ReadCVEPolicy(name string) (CVEPolicy, error)
+	UpdateCVEPolicy(ctx context.Context, p *CVEPolicy) (CVEPolicy, error)
+	ReadCVEPolicy(ctx context.Context) (CVEPolicy, error)
 }
 
 // CVEPolicy is a Twistlock CVE policy.
@@ -74,6 +75,31 @@ type CVEVulnerability struct {
 // 9 - critical
 type CVSSv3 float64
 
+// CVSSv3 severities Twistlock uses, each the lowest score of its rating.
+const (
+	CVSSv3Low      CVSSv3 = 0
+	CVSSv3Medium   CVSSv3 = 4
+	CVSSv3High     CVSSv3 = 7
+	CVSSv3Critical CVSSv3 = 9
+)
+
+// CVSSv3Severities are the CVSSv3 values Twistlock uses, lowest first.
+var CVSSv3Severities = []CVSSv3{CVSSv3Low, CVSSv3Medium, CVSSv3High, CVSSv3Critical}
+
+// Rating returns the qualitative rating of a score, e.g. "high" for 7.5.
+func (s CVSSv3) Rating() string {
+	switch {
+	case s >= CVSSv3Critical:
+		return "critical"
+	case s >= CVSSv3High:
+		return "high"
+	case s >= CVSSv3Medium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
 type CVERule struct {
 	IDs       []string
 	Effect    CVEEffect
@@ -89,6 +115,9 @@ const (
 	CVEEffectEmpty  = ""
 )
 
+// CVEEffects are the effects a CVERule may configure.
+var CVEEffects = []CVEEffect{CVEEffectIgnore, CVEEffectAlert, CVEEffectBlock}
+
 func (e *CVEEffect) UnmarshalText(text []byte) error {
 	switch string(text) {
 	case "ignore":
@@ -0,0 +1,46 @@
+package model
+
+import "fmt"
+
+// Permission is something a Console user's role may or may not allow, phrased
+// to complete "which cannot ...".
+type Permission string
+
+const (
+	PermissionManageUsers     Permission = "manage users"
+	PermissionManageCVEPolicy Permission = "manage the CVE policy"
+	PermissionManageRoles     Permission = "manage roles"
+)
+
+// rolePermissions lists what each built-in role may do. Roles that aren't
+// listed can't manage anything the provider supports.
+var rolePermissions = map[UserRole][]Permission{
+	RoleAdmin:    {PermissionManageUsers, PermissionManageCVEPolicy, PermissionManageRoles},
+	RoleOperator: {PermissionManageCVEPolicy},
+}
+
+// Can reports whether users with built-in role r have permission p, see
+// Role.Can for custom roles.
+func (r UserRole) Can(p Permission) bool {
+	for _, allowed := range rolePermissions[r] {
+		if allowed == p {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionError is returned for operations the Console's user is known not
+// to be allowed to perform.
+type PermissionError struct {
+	Username   string
+	Role       UserRole
+	Permission Permission
+}
+
+func (e *PermissionError) Error() string {
+	if e.Role == "" {
+		return fmt.Sprintf("user %s cannot %s", e.Username, e.Permission)
+	}
+	return fmt.Sprintf("user %s has role %s, which cannot %s", e.Username, e.Role, e.Permission)
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCan(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(RoleAdmin.Can(PermissionManageUsers))
+	assert.True(RoleAdmin.Can(PermissionManageCVEPolicy))
+	assert.False(RoleOperator.Can(PermissionManageUsers))
+	assert.True(RoleOperator.Can(PermissionManageCVEPolicy))
+	assert.False(RoleCI.Can(PermissionManageUsers))
+	assert.False(RoleAuditor.Can(PermissionManageCVEPolicy))
+
+	assert.EqualError(&PermissionError{Username: "ci_bot", Role: RoleCI, Permission: PermissionManageUsers},
+		"user ci_bot has role ci, which cannot manage users")
+	assert.EqualError(&PermissionError{Username: "ci_bot", Permission: PermissionManageUsers},
+		"user ci_bot cannot manage users")
+}
+
+func TestCustomRoleCan(t *testing.T) {
+	assert := assert.New(t)
+
+	policyEditor := Role{
+		Name: "policy-editor",
+		Permissions: []RolePermission{
+			{Name: RoleAreaPolicies, ReadWrite: true},
+			{Name: RoleAreaUsers},
+		},
+	}
+	assert.True(policyEditor.Can(PermissionManageCVEPolicy))
+	assert.False(policyEditor.Can(PermissionManageUsers), "read-only access to users")
+	assert.False(policyEditor.Can(PermissionManageRoles))
+
+	assert.True(Role{Name: "admin", System: true}.Can(PermissionManageRoles))
+	assert.False(Role{Name: "auditor", System: true}.Can(PermissionManageCVEPolicy))
+
+	assert.False(UserRole("policy-editor").BuiltIn())
+	assert.True(RoleDefenderManager.BuiltIn())
+}
@@ -0,0 +1,65 @@
+package model
+
+import "context"
+
+// RoleService manages the roles users of a Twistlock Console can have. The
+// Console lists its built-in roles as system roles, which cannot be changed.
+type RoleService interface {
+	// ListRoles returns every role, built-in and custom
+	ListRoles(ctx context.Context) ([]Role, error)
+	// ReadRole looks a role up by name, reporting whether it was found
+	ReadRole(ctx context.Context, name string) (Role, bool, error)
+	CreateRole(ctx context.Context, r *Role) (Role, error)
+	UpdateRole(ctx context.Context, r *Role) (Role, error)
+	DeleteRole(ctx context.Context, name string) error
+}
+
+// Role is a Twistlock RBAC role.
+type Role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// System is set for the built-in roles
+	System      bool             `json:"system,omitempty"`
+	Permissions []RolePermission `json:"perms"`
+}
+
+// RolePermission grants a role access to one area of the Console, e.g.
+// "policies" or "defenders". Without ReadWrite the access is read-only.
+type RolePermission struct {
+	Name      string `json:"name"`
+	ReadWrite bool   `json:"readWrite"`
+}
+
+// Custom roles were introduced in Console release 19.07.
+const (
+	CustomRolesMajor = 19
+	CustomRolesMinor = 7
+)
+
+// Areas of the Console that custom roles grant access to, as far as the
+// provider is concerned.
+const (
+	RoleAreaUsers    = "user"
+	RoleAreaPolicies = "policies"
+)
+
+// permissionAreas maps each Permission to the area a custom role must have
+// read-write access to for it.
+var permissionAreas = map[Permission]string{
+	PermissionManageUsers:     RoleAreaUsers,
+	PermissionManageRoles:     RoleAreaUsers,
+	PermissionManageCVEPolicy: RoleAreaPolicies,
+}
+
+// Can reports whether users with role r have permission p.
+func (r Role) Can(p Permission) bool {
+	if r.System {
+		return UserRole(r.Name).Can(p)
+	}
+	for _, perm := range r.Permissions {
+		if perm.Name == permissionAreas[p] && perm.ReadWrite {
+			return true
+		}
+	}
+	return false
+}
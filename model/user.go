@@ -1,15 +1,21 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
 
+// UserService manages users on a Twistlock Console.
 type UserService interface {
-	CreateUser(u *User) (User, error)
-	UpdateUser(u *User) (User, error)
-	DeleteUser(u *User) error
-	ReadUser(id string) (User, error)
+	CreateUser(ctx context.Context, u *User) (User, error)
+	UpdateUser(ctx context.Context, u *User) (User, error)
+	DeleteUser(ctx context.Context, u *User) error
+	// ReadUser looks a user up by `_id`, reporting whether it was found
+	ReadUser(ctx context.Context, id string) (User, bool, error)
+	// ReadUserByName looks a user up by username, reporting whether it was
+	// found
+	ReadUserByName(ctx context.Context, username string) (User, bool, error)
 }
 
 type User struct {
@@ -37,34 +43,49 @@ const (
 	RoleCI              UserRole = "ci"
 )
 
+// UserRoles are the Console's built-in roles.
+var UserRoles = []UserRole{RoleAdmin, RoleOperator, RoleDefenderManager, RoleAuditor, RoleUser, RoleCI}
+
+// UnmarshalText accepts the built-in roles and the name of any custom role,
+// which only the Console can check.
 func (r *UserRole) UnmarshalText(text []byte) error {
-	switch string(text) {
-	case "admin":
-		*r = RoleAdmin
-	case "operator":
-		*r = RoleOperator
-	case "defenderManager":
-		*r = RoleDefenderManager
-	case "auditor":
-		*r = RoleAuditor
-	case "user":
-		*r = RoleUser
-	case "ci":
-		*r = RoleCI
-	default:
-		return fmt.Errorf("Invalid UserRole: %s", string(text))
+	if len(text) == 0 {
+		return fmt.Errorf("Invalid UserRole: role must not be empty")
 	}
+	*r = UserRole(text)
 	return nil
 }
 
+// BuiltIn reports whether r is one of the Console's built-in roles rather
+// than a custom role.
+func (r UserRole) BuiltIn() bool {
+	for _, builtIn := range UserRoles {
+		if r == builtIn {
+			return true
+		}
+	}
+	return false
+}
+
 type UserAuthType string
 
 const (
 	AuthTypeBasic UserAuthType = "basic"
 	AuthTypeLDAP  UserAuthType = "ldap"
 	AuthTypeSAML  UserAuthType = "saml"
+	AuthTypeOIDC  UserAuthType = "oidc"
+	AuthTypeOAuth UserAuthType = "oauth"
 )
 
+// UserAuthTypes are the auth types UserAuthType.UnmarshalText accepts.
+var UserAuthTypes = []UserAuthType{AuthTypeBasic, AuthTypeLDAP, AuthTypeSAML, AuthTypeOIDC, AuthTypeOAuth}
+
+// HasPassword reports whether users of this auth type log in with a
+// password set on the Console, rather than through an identity provider.
+func (a UserAuthType) HasPassword() bool {
+	return a == AuthTypeBasic
+}
+
 func (a *UserAuthType) UnmarshalText(text []byte) error {
 	switch string(text) {
 	case "basic":
@@ -73,6 +94,10 @@ func (a *UserAuthType) UnmarshalText(text []byte) error {
 		*a = AuthTypeLDAP
 	case "saml":
 		*a = AuthTypeSAML
+	case "oidc":
+		*a = AuthTypeOIDC
+	case "oauth":
+		*a = AuthTypeOAuth
 	default:
 		return fmt.Errorf("Invalid UserAuthType: %s", string(text))
 	}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a Twistlock Console release such as 19.11.512. The zero Version
+// means the release is unknown.
+type Version struct {
+	Major int
+	Minor int
+	Build int
+}
+
+// ParseVersion parses a Console release as reported by its /version endpoint,
+// e.g. "19.11.512". The build number is optional.
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("Invalid Console version: %q", s)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("Invalid Console version: %q", s)
+		}
+		numbers[i] = n
+	}
+
+	return Version{Major: numbers[0], Minor: numbers[1], Build: numbers[2]}, nil
+}
+
+func (v Version) String() string {
+	if v == (Version{}) {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%02d.%d", v.Major, v.Minor, v.Build)
+}
+
+// AtLeast reports whether v is release major.minor or later.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+// Require returns an UnsupportedError when v is older than major.minor.
+// Features are assumed to be supported by Consoles of unknown version.
+func (v Version) Require(feature string, major, minor int) error {
+	if v == (Version{}) || v.AtLeast(major, minor) {
+		return nil
+	}
+	return &UnsupportedError{Feature: feature, Console: v, Required: Version{Major: major, Minor: minor}}
+}
+
+// UnsupportedError is returned for operations the Console's release doesn't
+// support.
+type UnsupportedError struct {
+	Feature  string
+	Console  Version
+	Required Version
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is unsupported on Console %s, it requires %d.%02d or later",
+		e.Feature, e.Console, e.Required.Major, e.Required.Minor)
+}
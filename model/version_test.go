@@ -0,0 +1,42 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	for s, expected := range map[string]Version{
+		"19.11.512":  {Major: 19, Minor: 11, Build: 512},
+		"22.06.179":  {Major: 22, Minor: 6, Build: 179},
+		"v20.04":     {Major: 20, Minor: 4},
+		" 19.07.363": {Major: 19, Minor: 7, Build: 363},
+	} {
+		v, err := ParseVersion(s)
+		assert.Nil(err, s)
+		assert.Equal(expected, v, s)
+	}
+
+	for _, s := range []string{"", "19", "19.x", "19.11.512.1", "-1.0"} {
+		_, err := ParseVersion(s)
+		assert.NotNil(err, s)
+	}
+}
+
+func TestVersionRequire(t *testing.T) {
+	assert := assert.New(t)
+	v := Version{Major: 20, Minor: 4, Build: 169}
+
+	assert.Equal("20.04.169", v.String())
+	assert.True(v.AtLeast(19, 11))
+	assert.True(v.AtLeast(20, 4))
+	assert.False(v.AtLeast(20, 9))
+	assert.False(v.AtLeast(21, 0))
+
+	assert.Nil(v.Require("custom roles", 20, 4))
+	assert.EqualError(v.Require("custom roles", 21, 8), "custom roles is unsupported on Console 20.04.169, it requires 21.08 or later")
+	assert.Nil(Version{}.Require("custom roles", 21, 8))
+}
@@ -0,0 +1,42 @@
+package password
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPrefix marks hashes made by Hash. The password is hashed with SHA-256
+// before bcrypt, which ignores everything past 72 bytes.
+const hashPrefix = "sha256-bcrypt:"
+
+// Hash returns a salted hash of `password` that is safe to store where the
+// password itself must not be, e.g. Terraform state.
+func Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(prehash(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return hashPrefix + string(hash), nil
+}
+
+// IsHash reports whether `s` was returned by Hash.
+func IsHash(s string) bool {
+	return strings.HasPrefix(s, hashPrefix)
+}
+
+// Matches reports whether `hash`, as returned by Hash, is a hash of
+// `password`.
+func Matches(hash, password string) bool {
+	if !IsHash(hash) {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(hash, hashPrefix)), prehash(password)) == nil
+}
+
+func prehash(password string) []byte {
+	sum := sha256.Sum256([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
+}
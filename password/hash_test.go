@@ -0,0 +1,32 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	assert := assert.New(t)
+
+	hash, err := Hash("hunter2")
+	assert.Nil(err)
+	assert.True(IsHash(hash))
+	assert.NotContains(hash, "hunter2")
+	assert.True(Matches(hash, "hunter2"))
+	assert.False(Matches(hash, "hunter3"))
+
+	again, err := Hash("hunter2")
+	assert.Nil(err)
+	assert.NotEqual(hash, again, "hashes are salted")
+
+	assert.False(Matches("hunter2", "hunter2"), "plaintext is not a hash")
+	assert.False(IsHash("hunter2"))
+
+	// bcrypt alone only looks at the first 72 bytes
+	long := strings.Repeat("a", 80)
+	hash, err = Hash(long)
+	assert.Nil(err)
+	assert.False(Matches(hash, long[:72]+"bbbbbbbb"))
+}
@@ -3,40 +3,180 @@ package password
 import (
 	crypto "crypto/rand"
 	"fmt"
-	"math"
 	"math/big"
-	"math/rand"
+	"strings"
 )
 
 const DefaultCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!\"$%^&*(){} <>?/\\#';:"
 
-func newRand() (*rand.Rand, error) {
-	max := big.NewInt(math.MaxInt64)
-	seed, err := crypto.Int(crypto.Reader, max)
-	if err != nil {
-		return nil, err
+// MinLength is the shortest password a Policy may generate.
+const MinLength = 8
+
+// Policy describes the passwords to generate.
+type Policy struct {
+	// Length is the number of characters, at least MinLength
+	Length uint
+	// Charset are the characters to choose from, DefaultCharset when empty
+	Charset string
+	// Exclude are characters removed from Charset, e.g. quotes and spaces
+	// that trip up tools the password is passed to
+	Exclude string
+
+	// MinUpper, MinLower, MinDigit and MinSymbol are the fewest ASCII
+	// uppercase letters, lowercase letters, digits and other characters the
+	// password may contain
+	MinUpper  uint
+	MinLower  uint
+	MinDigit  uint
+	MinSymbol uint
+}
+
+// DefaultPolicy generates 30 characters from DefaultCharset.
+var DefaultPolicy = Policy{Length: 30}
+
+// class is a group of characters the policy may require a minimum of.
+type class struct {
+	name  string
+	chars []byte
+	min   uint
+}
+
+// classes splits the policy's characters into uppercase, lowercase, digits
+// and symbols, each with its minimum count.
+func (p Policy) classes() []class {
+	classes := []class{
+		{name: "uppercase letters", min: p.MinUpper},
+		{name: "lowercase letters", min: p.MinLower},
+		{name: "digits", min: p.MinDigit},
+		{name: "symbols", min: p.MinSymbol},
+	}
+	for _, c := range p.charset() {
+		switch {
+		case 'A' <= c && c <= 'Z':
+			classes[0].chars = append(classes[0].chars, c)
+		case 'a' <= c && c <= 'z':
+			classes[1].chars = append(classes[1].chars, c)
+		case '0' <= c && c <= '9':
+			classes[2].chars = append(classes[2].chars, c)
+		default:
+			classes[3].chars = append(classes[3].chars, c)
+		}
+	}
+	return classes
+}
+
+// charset returns the characters passwords are drawn from, without
+// duplicates so that every character is equally likely.
+func (p Policy) charset() []byte {
+	charset := p.Charset
+	if charset == "" {
+		charset = DefaultCharset
 	}
 
-	return rand.New(rand.NewSource(seed.Int64())), nil
+	seen := map[byte]bool{}
+	var chars []byte
+	for i := 0; i < len(charset); i++ {
+		c := charset[i]
+		if seen[c] || strings.IndexByte(p.Exclude, c) >= 0 {
+			continue
+		}
+		seen[c] = true
+		chars = append(chars, c)
+	}
+	return chars
 }
 
-// RandomString returns a string of comprised of `length` randomly chosen
-// characters from DefaultCharset.
-//
-// length must be at least 8
-func RandomString(length uint) (string, error) {
-	if length < 8 {
-		return "", fmt.Errorf("length must be at least 8 characters")
+// Validate returns an error when no password can satisfy the policy.
+func (p Policy) Validate() error {
+	if p.Length < MinLength {
+		return fmt.Errorf("length must be at least %d characters", MinLength)
 	}
 
-	r, err := newRand()
-	if err != nil {
+	for i := 0; i < len(p.Charset); i++ {
+		if p.Charset[i] >= 0x80 {
+			return fmt.Errorf("charset must only contain ASCII characters")
+		}
+	}
+
+	if len(p.charset()) == 0 {
+		return fmt.Errorf("charset has no characters left after excluding %q", p.Exclude)
+	}
+
+	var required uint
+	for _, c := range p.classes() {
+		if c.min > 0 && len(c.chars) == 0 {
+			return fmt.Errorf("at least %d %s are required but the charset has none", c.min, c.name)
+		}
+		required += c.min
+	}
+	if required > p.Length {
+		return fmt.Errorf("the minimum counts add up to %d characters, more than the length of %d", required, p.Length)
+	}
+
+	return nil
+}
+
+// Generate returns a random password satisfying the policy. Every character
+// is drawn uniformly from crypto/rand.
+func (p Policy) Generate() (string, error) {
+	if err := p.Validate(); err != nil {
 		return "", err
 	}
 
-	chars := make([]byte, length)
-	for i := uint(0); i < length; i++ {
-		chars[i] = DefaultCharset[r.Intn(len(DefaultCharset))]
+	chars := make([]byte, 0, p.Length)
+	for _, c := range p.classes() {
+		for i := uint(0); i < c.min; i++ {
+			char, err := choose(c.chars)
+			if err != nil {
+				return "", err
+			}
+			chars = append(chars, char)
+		}
+	}
+
+	charset := p.charset()
+	for uint(len(chars)) < p.Length {
+		char, err := choose(charset)
+		if err != nil {
+			return "", err
+		}
+		chars = append(chars, char)
 	}
+
+	// Shuffle so that the required characters aren't always first
+	for i := len(chars) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+
 	return string(chars), nil
 }
+
+// choose returns a uniformly random element of chars.
+func choose(chars []byte) (byte, error) {
+	i, err := randomInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// randomInt returns a uniformly random int in [0, n).
+func randomInt(n int) (int, error) {
+	i, err := crypto.Int(crypto.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// RandomString returns a string of comprised of `length` randomly chosen
+// characters from DefaultCharset.
+//
+// length must be at least 8
+func RandomString(length uint) (string, error) {
+	return Policy{Length: length}.Generate()
+}
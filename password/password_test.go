@@ -1,7 +1,9 @@
 package password
 
 import (
+	"strings"
 	"testing"
+	"unicode"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +34,57 @@ func TestRandomString(t *testing.T) {
 	assert.Equal(20, len(second))
 	assert.NotEqual(first, second)
 }
+
+func TestPolicyValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(DefaultPolicy.Validate())
+	assert.Nil(Policy{Length: 8, MinUpper: 2, MinLower: 2, MinDigit: 2, MinSymbol: 2}.Validate())
+
+	assert.EqualError(Policy{Length: 7}.Validate(), "length must be at least 8 characters")
+	assert.EqualError(Policy{Length: 8, Charset: "abc", Exclude: "cba"}.Validate(), `charset has no characters left after excluding "cba"`)
+	assert.EqualError(Policy{Length: 8, Charset: "abc123", MinUpper: 1}.Validate(), "at least 1 uppercase letters are required but the charset has none")
+	assert.EqualError(Policy{Length: 8, MinDigit: 5, MinSymbol: 4}.Validate(), "the minimum counts add up to 9 characters, more than the length of 8")
+	assert.EqualError(Policy{Length: 8, Charset: "abcé"}.Validate(), "charset must only contain ASCII characters")
+}
+
+func TestPolicyGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := Policy{
+		Length:    16,
+		Exclude:   "\"\\ ",
+		MinUpper:  3,
+		MinLower:  3,
+		MinDigit:  3,
+		MinSymbol: 3,
+	}
+
+	for i := 0; i < 100; i++ {
+		result, err := policy.Generate()
+		if !assert.Nil(err) {
+			return
+		}
+		assert.Len(result, 16)
+		assert.False(strings.ContainsAny(result, "\"\\ "), "%q contains an excluded character", result)
+
+		var upper, lower, digit, symbol uint
+		for _, c := range result {
+			switch {
+			case unicode.IsUpper(c):
+				upper++
+			case unicode.IsLower(c):
+				lower++
+			case unicode.IsDigit(c):
+				digit++
+			default:
+				symbol++
+			}
+		}
+		assert.True(upper >= 3 && lower >= 3 && digit >= 3 && symbol >= 3, "%q doesn't satisfy the minimums", result)
+	}
+
+	result, err := Policy{Length: 10, Charset: "ab"}.Generate()
+	assert.Nil(err)
+	assert.Regexp("^[ab]{10}$", result)
+}
@@ -0,0 +1,104 @@
+package twistlock
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+
+	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+// defaultTimeouts are the operation timeouts used by every resource unless
+// overridden with a `timeouts` block.
+func defaultTimeouts() *schema.ResourceTimeout {
+	return &schema.ResourceTimeout{
+		Create: schema.DefaultTimeout(5 * time.Minute),
+		Read:   schema.DefaultTimeout(5 * time.Minute),
+		Update: schema.DefaultTimeout(5 * time.Minute),
+		Delete: schema.DefaultTimeout(5 * time.Minute),
+	}
+}
+
+// operationContext returns a context for one resource operation. It expires
+// after the resource's timeout for `key`, one of schema.TimeoutCreate,
+// TimeoutRead, TimeoutUpdate or TimeoutDelete, and is scoped to the
+// resource's project when it overrides the provider's.
+func operationContext(d *schema.ResourceData, key string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(key))
+	if project, ok := d.GetOk("project"); ok {
+		ctx = client.WithProject(ctx, project.(string))
+	}
+	return ctx, cancel
+}
+
+// diffContext is operationContext for requests made while planning. Timeouts
+// blocks aren't available to CustomizeDiff, so it expires after the default
+// read timeout.
+func diffContext(d *schema.ResourceDiff) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), *defaultTimeouts().Read)
+	if project, ok := d.GetOk("project"); ok {
+		ctx = client.WithProject(ctx, project.(string))
+	}
+	return ctx, cancel
+}
+
+// changeContext is operationContext for operations that change the Console,
+// it records `resourceType` as the origin of the changes in the audit log.
+func changeContext(d *schema.ResourceData, resourceType, key string) (context.Context, context.CancelFunc) {
+	ctx, cancel := operationContext(d, key)
+	return client.WithResourceType(ctx, resourceType), cancel
+}
+
+// authorize fails plans that change a resource needing permission `p` when
+// the provider's user is known not to have it, see validate_on_configure.
+func authorize(p model.Permission) schema.CustomizeDiffFunc {
+	return func(_ *schema.ResourceDiff, m interface{}) error {
+		return m.(model.Console).Authorize(p)
+	}
+}
+
+// allDiffs runs every one of `funcs` in order, stopping at the first error.
+func allDiffs(funcs ...schema.CustomizeDiffFunc) schema.CustomizeDiffFunc {
+	return func(d *schema.ResourceDiff, m interface{}) error {
+		for _, f := range funcs {
+			if err := f(d, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// resourceGetter is implemented by both schema.ResourceData and
+// schema.ResourceDiff, so that the same code can read a resource's
+// configuration at plan and apply time.
+type resourceGetter interface {
+	Get(key string) interface{}
+}
+
+// projectSchema is the `project` attribute every resource uses to override
+// the provider's Twistlock project.
+func projectSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		ForceNew:    true,
+		Description: "Twistlock project to manage this object in, defaults to the provider's project",
+	}
+}
+
+// importProject splits an import ID of the form `<project>/<id>` and stores
+// the project in `d`, returning the remaining ID. IDs without a `/` are in
+// the provider's project.
+func importProject(d *schema.ResourceData) string {
+	parts := strings.SplitN(d.Id(), "/", 2)
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	d.Set("project", parts[0])
+	return parts[1]
+}
@@ -1,39 +1,202 @@
 package twistlock
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/model"
+	"github.com/hashicorp/terraform/helper/logging"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
-func configureProvider(d *schema.ResourceData) (interface{}, error) {
-	return client.NewClient(d.Get("username").(string),
-		d.Get("password").(string),
-		d.Get("base_url").(string),
-		d.Get("tls_skip_verify").(bool)), nil
+func configureProvider(d *schema.ResourceData) (model.Console, error) {
+	var authMethod client.AuthMethod
+	if err := authMethod.UnmarshalText([]byte(d.Get("auth_method").(string))); err != nil {
+		return nil, err
+	}
+
+	retryMaxWait, err := time.ParseDuration(d.Get("retry_max_wait").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	requestTimeout, err := time.ParseDuration(d.Get("request_timeout").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	minTLSVersion, err := client.ParseTLSVersion(d.Get("tls_min_version").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	caCertPEM := d.Get("ca_cert_pem").(string)
+	if path := d.Get("ca_cert_file").(string); path != "" {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read ca_cert_file: %s", err)
+		}
+		caCertPEM = string(pem)
+	}
+
+	username, password := d.Get("username").(string), d.Get("password").(string)
+	if path := d.Get("password_file").(string); path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read password_file: %s", err)
+		}
+		password = strings.TrimRight(string(contents), "\r\n")
+	}
+
+	var credentialsSource client.CredentialsSource
+	if command := d.Get("credentials_command").([]interface{}); len(command) > 0 {
+		argv := make([]string, len(command))
+		for i, arg := range command {
+			argv[i] = arg.(string)
+		}
+		credentialsSource = client.CommandCredentials(argv)
+	}
+
+	accessKey, secretKey := d.Get("access_key").(string), d.Get("secret_key").(string)
+	switch {
+	case accessKey != "" && secretKey == "":
+		return nil, fmt.Errorf("secret_key is required when access_key is set")
+	case accessKey == "" && credentialsSource == nil && (username == "" || password == ""):
+		return nil, fmt.Errorf("Either username and password, access_key and secret_key or credentials_command are required")
+	}
+
+	var failoverURLs []string
+	for _, u := range d.Get("failover_urls").([]interface{}) {
+		failoverURLs = append(failoverURLs, u.(string))
+	}
+
+	var auditHook client.AuditHook
+	if path := d.Get("audit_log_path").(string); path != "" {
+		// The log stays open for the life of the provider process, which
+		// ends with the Terraform run
+		auditLog, err := client.OpenAuditLog(path)
+		if err != nil {
+			return nil, err
+		}
+		auditHook = func(r client.AuditRecord) {
+			if err := auditLog.Record(r); err != nil {
+				log.Printf("[ERROR] Failed to write audit log: %s", err)
+			}
+		}
+	}
+
+	c, err := client.NewClient(client.Config{
+		Username:       username,
+		Password:       password,
+		AccessKey:      accessKey,
+		SecretKey:      secretKey,
+		BaseURL:        d.Get("base_url").(string),
+		FailoverURLs:   failoverURLs,
+		SkipTLSVerify:  d.Get("tls_skip_verify").(bool),
+		CACertPEM:      caCertPEM,
+		ClientCertPEM:  d.Get("client_cert_pem").(string),
+		ClientKeyPEM:   d.Get("client_key_pem").(string),
+		MinTLSVersion:  minTLSVersion,
+		AuthMethod:     authMethod,
+		MaxRetries:     d.Get("max_retries").(int),
+		RetryMaxWait:   retryMaxWait,
+		RequestTimeout: requestTimeout,
+		Project:        d.Get("project").(string),
+		LogRequests:    logging.IsDebugOrHigher(),
+
+		MaxRequestsPerSecond:  d.Get("max_requests_per_second").(float64),
+		MaxConcurrentRequests: d.Get("max_concurrent_requests").(int),
+		CredentialsSource:     credentialsSource,
+		ReadOnly:              d.Get("read_only").(bool),
+		AuditHook:             auditHook,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Validation goes through the API path picked from the version, like
+	// every later request
+	if _, err := c.DetectVersion(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if d.Get("validate_on_configure").(bool) {
+		if err := c.Validate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
 
+// ConsoleWrapper decorates the Console client built from the provider
+// configuration, e.g. to add caching or auditing, before resources use it.
+type ConsoleWrapper func(model.Console) (model.Console, error)
+
+// Provider returns the Twistlock provider.
 func Provider() *schema.Provider {
+	return NewProvider()
+}
+
+// NewProvider returns the Twistlock provider, passing the configured client
+// through `wrappers` in order. The outermost wrapper is what resources call.
+func NewProvider(wrappers ...ConsoleWrapper) *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"username": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_USERNAME", os.Getenv("TWISTLOCK_USERNAME")),
 				Description: "Username to log in with",
 			},
 			"password": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_PASSWORD", os.Getenv("TWISTLOCK_PASSWORD")),
 				Description: "Password to log in with",
 			},
+			"password_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_PASSWORD_FILE", ""),
+				Description: "Path to a file holding the password to log in with, takes precedence over password",
+			},
+			"credentials_command": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Command, followed by its arguments, printing JSON with either `username` and `password` or `token`. It is run whenever a new token is needed and takes precedence over username and password",
+			},
+			"access_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_ACCESS_KEY", ""),
+				Description: "Prisma Cloud access key ID to log in to a Prisma Cloud Compute SaaS Console with, takes precedence over username and password",
+			},
+			"secret_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_SECRET_KEY", ""),
+				Description: "Prisma Cloud secret key for access_key",
+			},
 			"base_url": {
 				Type:        schema.TypeString,
 				Required:    true,
 				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_BASE_URL", os.Getenv("TWISTLOCK_BASE_URL")),
-				Description: "Base URL for the Twistlock API, e.g. http://localhost:8081/api/v1",
+				Description: "URL of the Twistlock Console, e.g. https://console:8083, or of a Prisma Cloud Compute SaaS Console including its tenant, e.g. https://us-east1.cloud.twistlock.com/us-2-123456789. The API path is picked from the Console's version unless included, e.g. http://localhost:8081/api/v1",
+			},
+			"failover_urls": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "URLs of other Twistlock Consoles of an HA deployment, tried in order when the Console in use has a connection error or returns a 5xx response. Requests that create or change resources (POST) only fail over when the Console refused the connection, since one that timed out or failed may still have been applied",
 			},
 			"tls_skip_verify": {
 				Type:        schema.TypeBool,
@@ -41,12 +204,122 @@ func Provider() *schema.Provider {
 				Default:     false,
 				Description: "Trust self-signed certificates presented by the Twistlock Console",
 			},
+			"ca_cert_pem": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"ca_cert_file"},
+				Description:   "PEM encoded CA certificates to verify the Twistlock Console's certificate with, instead of the system roots",
+			},
+			"ca_cert_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("TWISTLOCK_CA_CERT_FILE", ""),
+				ConflictsWith: []string{"ca_cert_pem"},
+				Description:   "Path to a file of PEM encoded CA certificates to verify the Twistlock Console's certificate with",
+			},
+			"client_cert_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM encoded client certificate for Twistlock Consoles that require mutual TLS",
+			},
+			"client_key_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "PEM encoded private key for client_cert_pem",
+			},
+			"tls_min_version": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "",
+				ValidateFunc: validateTLSVersion,
+				Description:  "Minimum TLS version to accept from the Twistlock Console, one of 1.0, 1.1, 1.2 or 1.3. Go's default minimum applies when unset",
+			},
+			"validate_on_configure": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Check that the Twistlock Console is reachable and accepts the credentials when the provider is configured, and fail plans for resources the user's role cannot manage",
+			},
+			"read_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_READ_ONLY", false),
+				Description: "Refuse every change to the Twistlock Console, so that plans can detect drift without any risk of applying changes",
+			},
+			"audit_log_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_AUDIT_LOG_PATH", ""),
+				Description: "Path to a file to append a JSON line to for every change made to the Twistlock Console, with the object before and after the change",
+			},
+			"project": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TWISTLOCK_PROJECT", ""),
+				Description: "Twistlock project to manage, defaults to the master project",
+			},
+			"auth_method": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("TWISTLOCK_AUTH_METHOD", string(client.AuthMethodBasic)),
+				ValidateFunc: validateOneOf([]string{string(client.AuthMethodBasic), string(client.AuthMethodToken)}),
+				Description:  "How to authenticate with the Twistlock Console, either `basic` or `token`. Access keys always use `token`",
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      3,
+				ValidateFunc: validateNonNegativeInt,
+				Description:  "How many times to retry a request that failed with a connection error or a 429, 502, 503 or 504 response",
+			},
+			"retry_max_wait": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "30s",
+				ValidateFunc: validateDuration,
+				Description:  "Longest time to wait between two attempts of a request, e.g. 30s",
+			},
+			"request_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "1m",
+				ValidateFunc: validateDuration,
+				Description:  "Longest time a single request to the Twistlock Console may take, 0s disables the limit",
+			},
+			"max_requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				Default:      0.0,
+				ValidateFunc: validateNonNegativeFloat,
+				Description:  "Average number of requests per second to send to the Twistlock Console, 0 disables the limit",
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validateNonNegativeInt,
+				Description:  "Number of requests that may be in flight to the Twistlock Console at once, 0 disables the limit",
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"twistlock_user":         resourceUser(),
 			"twistlock_machine_user": resourceMachineUser(),
 			"twistlock_cve_policy":   resourceCVEPolicy(),
+			"twistlock_role":         resourceRole(),
+		},
+		ConfigureFunc: func(d *schema.ResourceData) (interface{}, error) {
+			console, err := configureProvider(d)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, wrap := range wrappers {
+				if console, err = wrap(console); err != nil {
+					return nil, err
+				}
+			}
+			return console, nil
 		},
-		ConfigureFunc: configureProvider,
 	}
 }
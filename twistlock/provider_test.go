@@ -1,16 +1,71 @@
 package twistlock
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
 )
 
 var testAccProviders map[string]terraform.ResourceProvider
 var testAccProvider *schema.Provider
 
+// testConsole is the fake console the resource tests run against when TF_ACC
+// is not set. It is nil when running against a real Twistlock Console.
+var testConsole *fakeconsole.Console
+
+func TestMain(m *testing.M) {
+	if os.Getenv(resource.TestEnvVar) == "" {
+		testConsole = fakeconsole.New("admin", "admin-password")
+		os.Setenv("TWISTLOCK_USERNAME", testConsole.Username)
+		os.Setenv("TWISTLOCK_PASSWORD", testConsole.Password)
+		os.Setenv("TWISTLOCK_BASE_URL", testConsole.URL())
+	}
+
+	code := m.Run()
+
+	if testConsole != nil {
+		testConsole.Close()
+	}
+	os.Exit(code)
+}
+
+// testResource runs a resource test case against a real Twistlock Console
+// when TF_ACC is set and against testConsole otherwise.
+func testResource(t *testing.T, c resource.TestCase) {
+	if testConsole != nil {
+		resource.UnitTest(t, c)
+		return
+	}
+	resource.Test(t, c)
+}
+
+// testFakeConsole returns testConsole, skipping the test when running against
+// a real Twistlock Console. Callers should clear any faults they inject.
+func testFakeConsole(t *testing.T) *fakeconsole.Console {
+	if testConsole == nil {
+		t.Skip("Fault injection requires the fake console, unset TF_ACC")
+	}
+	return testConsole
+}
+
 func init() {
 	testAccProvider = Provider()
 	testAccProviders = map[string]terraform.ResourceProvider{
@@ -37,3 +92,226 @@ func TestProvider(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+// namedConsole wraps a Console so tests can tell which wrappers were applied.
+type namedConsole struct {
+	model.Console
+	name string
+}
+
+func TestNewProviderWrapsConsole(t *testing.T) {
+	assert := assert.New(t)
+
+	wrap := func(name string) ConsoleWrapper {
+		return func(c model.Console) (model.Console, error) {
+			return namedConsole{c, name}, nil
+		}
+	}
+
+	raw, err := config.NewRawConfig(map[string]interface{}{
+		"username": os.Getenv("TWISTLOCK_USERNAME"),
+		"password": os.Getenv("TWISTLOCK_PASSWORD"),
+		"base_url": os.Getenv("TWISTLOCK_BASE_URL"),
+	})
+	assert.Nil(err)
+
+	p := NewProvider(wrap("inner"), wrap("outer"))
+	assert.Nil(p.Configure(terraform.NewResourceConfig(raw)))
+
+	outer, ok := p.Meta().(namedConsole)
+	assert.True(ok)
+	assert.Equal("outer", outer.name)
+
+	inner, ok := outer.Console.(namedConsole)
+	assert.True(ok)
+	assert.Equal("inner", inner.name)
+
+	_, ok = inner.Console.(*client.Client)
+	assert.True(ok)
+}
+
+func TestProviderAccessKeys(t *testing.T) {
+	assert := assert.New(t)
+	console := testFakeConsole(t)
+
+	configure := func(c map[string]interface{}) error {
+		c["base_url"] = console.ConsoleURL()
+		raw, err := config.NewRawConfig(c)
+		assert.Nil(err)
+		return Provider().Configure(terraform.NewResourceConfig(raw))
+	}
+
+	// The fake console accepts its users' credentials as access keys
+	assert.Nil(configure(map[string]interface{}{
+		"access_key": console.Username,
+		"secret_key": console.Password,
+	}))
+	assert.EqualError(configure(map[string]interface{}{
+		"access_key": "access-key-id",
+	}), "secret_key is required when access_key is set")
+}
+
+func TestProviderDetectsConsoleVersion(t *testing.T) {
+	assert := assert.New(t)
+	console := testFakeConsole(t)
+	console.SetVersion("22.06.179")
+	defer console.SetVersion(fakeconsole.DefaultVersion)
+
+	raw, err := config.NewRawConfig(map[string]interface{}{
+		"username": console.Username,
+		"password": console.Password,
+		"base_url": console.ConsoleURL(),
+	})
+	assert.Nil(err)
+
+	p := Provider()
+	assert.Nil(p.Configure(terraform.NewResourceConfig(raw)))
+	assert.Equal("22.06.179", p.Meta().(model.Console).Version().String())
+}
+
+func TestProviderValidateOnConfigure(t *testing.T) {
+	console := testFakeConsole(t)
+	console.AddUser(model.User{Username: "ci_bot", Role: model.RoleCI, AuthType: model.AuthTypeBasic}, "ci-password")
+	defer console.RemoveUser("ci_bot")
+
+	provider := func(password string) string {
+		return fmt.Sprintf(`
+			provider "twistlock" {
+				username = "ci_bot"
+				password = "%s"
+				validate_on_configure = true
+			}`, password)
+	}
+
+	testResource(t, resource.TestCase{
+		Providers: map[string]terraform.ResourceProvider{"twistlock": Provider()},
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: provider("wrong") + `
+					resource "twistlock_machine_user" "test_user" {
+						username = "bob"
+						password = "password"
+						role = "user"
+						auth_type = "basic"
+					}`,
+				ExpectError: regexp.MustCompile("rejected the credentials for ci_bot"),
+			},
+			resource.TestStep{
+				Config: provider("ci-password") + `
+					resource "twistlock_machine_user" "test_user" {
+						username = "bob"
+						password = "password"
+						role = "user"
+						auth_type = "basic"
+					}`,
+				ExpectError: regexp.MustCompile("user ci_bot has role ci, which cannot manage users"),
+			},
+		},
+	})
+}
+
+func TestProviderFailoverURLs(t *testing.T) {
+	assert := assert.New(t)
+	console := testFakeConsole(t)
+
+	// Nothing listens on a closed server's URL
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	raw, err := config.NewRawConfig(map[string]interface{}{
+		"username":      console.Username,
+		"password":      console.Password,
+		"base_url":      down.URL,
+		"failover_urls": []interface{}{console.ConsoleURL()},
+	})
+	assert.Nil(err)
+
+	p := Provider()
+	assert.Nil(p.Configure(terraform.NewResourceConfig(raw)))
+	_, err = p.Meta().(model.Console).ReadCVEPolicy(context.Background())
+	assert.Nil(err)
+}
+
+func TestProviderPasswordFile(t *testing.T) {
+	assert := assert.New(t)
+	console := testFakeConsole(t)
+
+	file, err := ioutil.TempFile("", "twistlock-password")
+	assert.Nil(err)
+	defer os.Remove(file.Name())
+	fmt.Fprintln(file, console.Password)
+	file.Close()
+
+	raw, err := config.NewRawConfig(map[string]interface{}{
+		"username":              console.Username,
+		"password":              "not-the-password",
+		"password_file":         file.Name(),
+		"base_url":              console.URL(),
+		"validate_on_configure": true,
+	})
+	assert.Nil(err)
+	assert.Nil(Provider().Configure(terraform.NewResourceConfig(raw)))
+}
+
+func TestProviderReadOnly(t *testing.T) {
+	username := acctest.RandString(8)
+	hcl := fmt.Sprintf(`
+		provider "twistlock" {
+			read_only = true
+		}
+
+		resource "twistlock_machine_user" "test_user" {
+			username = "%s"
+			password = "password"
+			role = "user"
+			auth_type = "basic"
+		}`, username)
+
+	testResource(t, resource.TestCase{
+		Providers: map[string]terraform.ResourceProvider{"twistlock": Provider()},
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:             hcl,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			resource.TestStep{
+				Config:      hcl,
+				ExpectError: regexp.MustCompile("Refusing to create user " + username + ": the Twistlock provider is read-only"),
+			},
+		},
+	})
+}
+
+func TestProviderAuditLog(t *testing.T) {
+	assert := assert.New(t)
+	console := testFakeConsole(t)
+
+	dir, err := ioutil.TempDir("", "twistlock-audit")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	raw, err := config.NewRawConfig(map[string]interface{}{
+		"username":       console.Username,
+		"password":       console.Password,
+		"base_url":       console.URL(),
+		"audit_log_path": path,
+	})
+	assert.Nil(err)
+
+	p := Provider()
+	assert.Nil(p.Configure(terraform.NewResourceConfig(raw)))
+	ctx := client.WithResourceType(context.Background(), "twistlock_cve_policy")
+	_, err = p.Meta().(model.Console).UpdateCVEPolicy(ctx, &model.CVEPolicy{})
+	assert.Nil(err)
+
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	var record client.AuditRecord
+	assert.Nil(json.Unmarshal(contents, &record))
+	assert.Equal("update", record.Operation)
+	assert.Equal("cve_policy", record.ObjectType)
+	assert.Equal("twistlock_cve_policy", record.ResourceType)
+	assert.Equal(http.StatusOK, record.Status)
+}
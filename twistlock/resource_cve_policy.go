@@ -1,9 +1,10 @@
 package twistlock
 
 import (
+	"context"
+	"fmt"
 	"log"
 
-	"github.com/circleci/terraform-provider-twistlock/client"
 	"github.com/circleci/terraform-provider-twistlock/model"
 	"github.com/hashicorp/terraform/helper/schema"
 )
@@ -14,8 +15,15 @@ func resourceCVEPolicy() *schema.Resource {
 		Read:   resourceCVEPolicyRead,
 		Update: resourceCVEPolicyUpdate,
 		Delete: resourceCVEPolicyDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceCVEPolicyImport,
+		},
+
+		Timeouts:      defaultTimeouts(),
+		CustomizeDiff: authorize(model.PermissionManageCVEPolicy),
 
 		Schema: map[string]*schema.Schema{
+			"project": projectSchema(),
 			"rules": {
 				Type:     schema.TypeList,
 				Required: true,
@@ -73,7 +81,7 @@ func resourceCVEPolicy() *schema.Resource {
 											Schema: map[string]*schema.Schema{
 												"id":               {Type: schema.TypeInt, Required: true},
 												"block":            {Type: schema.TypeBool, Required: true},
-												"minimum_severity": {Type: schema.TypeFloat, Required: true},
+												"minimum_severity": {Type: schema.TypeFloat, Required: true, ValidateFunc: validateCVSSv3Severity},
 											},
 										},
 									},
@@ -89,7 +97,7 @@ func resourceCVEPolicy() *schema.Resource {
 													Required: true,
 													Elem:     &schema.Schema{Type: schema.TypeString},
 												},
-												"effect":     {Type: schema.TypeString, Required: true},
+												"effect":     {Type: schema.TypeString, Required: true, ValidateFunc: validateCVEEffect},
 												"only_fixed": {Type: schema.TypeBool, Required: true},
 											},
 										},
@@ -235,16 +243,24 @@ func cvePolicyFromResource(d *schema.ResourceData) (*model.CVEPolicy, error) {
 	}, nil
 }
 
-func resourceCVEPolicyCreate(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
-
+// updateCVEPolicy replaces the Console's CVE policy with the one configured
+// in `d`.
+func updateCVEPolicy(ctx context.Context, d *schema.ResourceData, client model.Console) error {
 	policy, err := cvePolicyFromResource(d)
 	if err != nil {
 		return err
 	}
 
-	_, err = client.UpdateCVEPolicy(policy)
-	if err != nil {
+	_, err = client.UpdateCVEPolicy(ctx, policy)
+	return err
+}
+
+func resourceCVEPolicyCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_cve_policy", schema.TimeoutCreate)
+	defer cancel()
+
+	if err := updateCVEPolicy(ctx, d, client); err != nil {
 		return err
 	}
 
@@ -254,9 +270,11 @@ func resourceCVEPolicyCreate(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceCVEPolicyRead(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
+	client := m.(model.Console)
+	ctx, cancel := operationContext(d, schema.TimeoutRead)
+	defer cancel()
 
-	policy, err := client.ReadCVEPolicy()
+	policy, err := client.ReadCVEPolicy(ctx)
 	log.Printf("[INFO] resourceCVEPolicyRead - policy is %v", policy)
 
 	if err != nil {
@@ -274,8 +292,10 @@ func resourceCVEPolicyRead(d *schema.ResourceData, m interface{}) error {
 
 func resourceCVEPolicyUpdate(d *schema.ResourceData, m interface{}) error {
 	if d.HasChange("rules") {
-		err := resourceCVEPolicyCreate(d, m)
-		if err != nil {
+		ctx, cancel := changeContext(d, "twistlock_cve_policy", schema.TimeoutUpdate)
+		defer cancel()
+
+		if err := updateCVEPolicy(ctx, d, m.(model.Console)); err != nil {
 			return err
 		}
 	}
@@ -286,8 +306,11 @@ func resourceCVEPolicyUpdate(d *schema.ResourceData, m interface{}) error {
 func resourceCVEPolicyDelete(d *schema.ResourceData, m interface{}) error {
 	log.Print("[WARN] Cannot destroy the Twistlock CVE policy. Setting an empty policy.")
 
-	client := m.(client.Client)
-	_, err := client.UpdateCVEPolicy(&model.CVEPolicy{})
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_cve_policy", schema.TimeoutDelete)
+	defer cancel()
+
+	_, err := client.UpdateCVEPolicy(ctx, &model.CVEPolicy{})
 	if err != nil {
 		return err
 	}
@@ -296,3 +319,14 @@ func resourceCVEPolicyDelete(d *schema.ResourceData, m interface{}) error {
 
 	return nil
 }
+
+// resourceCVEPolicyImport adopts the Console's CVE policy, which always has
+// the ID "cve", optionally prefixed with `<project>/`.
+func resourceCVEPolicyImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if importProject(d) != "cve" {
+		return nil, fmt.Errorf("Cannot import Twistlock CVE policy '%s': the CVE policy ID is always 'cve'", d.Id())
+	}
+
+	d.SetId("cve")
+	return []*schema.ResourceData{d}, nil
+}
@@ -1,19 +1,21 @@
 package twistlock
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
-	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
 	"github.com/circleci/terraform-provider-twistlock/model"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
 )
 
 func TestAccCVEPolicy(t *testing.T) {
-	resource.Test(t, resource.TestCase{
+	testResource(t, resource.TestCase{
 		PreCheck: func() {
 			testAccPreCheck(t)
 		},
@@ -155,15 +157,37 @@ func TestAccCVEPolicy(t *testing.T) {
 					}),
 				),
 			},
+			resource.TestStep{
+				ResourceName:      "twistlock_cve_policy.test_cve_policy",
+				ImportState:       true,
+				ImportStateId:     "cve",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestCVEPolicy_MalformedResponse(t *testing.T) {
+	console := testFakeConsole(t)
+	console.InjectFault(fakeconsole.MalformedJSON("GET", "/policies/cve"))
+	defer console.ClearFaults()
+
+	testResource(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccCVEPolicy_BasicConfig(),
+				ExpectError: regexp.MustCompile("invalid character"),
+			},
 		},
 	})
 }
 
 func testAccCheckCreated(expectedPolicy model.CVEPolicy) func(s *terraform.State) error {
 	return func(s *terraform.State) error {
-		client := testAccProvider.Meta().(client.Client)
+		client := testAccProvider.Meta().(model.Console)
 
-		policy, err := client.ReadCVEPolicy()
+		policy, err := client.ReadCVEPolicy(context.Background())
 		if err != nil {
 			return err
 		}
@@ -184,9 +208,9 @@ func testAccCheckCreated(expectedPolicy model.CVEPolicy) func(s *terraform.State
 }
 
 func testAccCVEPolicyDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(client.Client)
+	client := testAccProvider.Meta().(model.Console)
 
-	cvePolicy, err := client.ReadCVEPolicy()
+	cvePolicy, err := client.ReadCVEPolicy(context.Background())
 	if err != nil {
 		return err
 	}
@@ -201,69 +225,77 @@ func testAccCVEPolicyDestroy(s *terraform.State) error {
 func testAccCVEPolicy_BasicConfig() string {
 	return `
 	resource "twistlock_machine_user" "test_user" {
-		"username" = "test-user"
-		"password" = "password"
-		"role" = "admin"
-		"auth_type" = "basic"
+		username = "test-user"
+		password = "password"
+		role = "admin"
+		auth_type = "basic"
 	}
 
 	resource "twistlock_cve_policy" "test_cve_policy" {
-		rules = [
-			{"owner" = "test_user"
-			 "name" = "Twistlock acceptance test CVE policy"
-			 "resources" {
-			 	"hosts" = ["*"]
-			 	"images" = ["*", "foo/*"]
-			 	"labels" = ["*"]
-			 	"containers" = ["*"]
-			 }
-			 "condition" = {
-			 	"vulnerabilities" = [
-				 	{"id" = 46, "block" = true, "minimum_severity" = 9}
-			 	]
-				"cves" = {
-				 	"ids" = ["CVE-2017-1234"]
-				 	"effect" = "alert"
-				 	"only_fixed" = true
-			 	}
+		rules {
+			owner = "test_user"
+			name = "Twistlock acceptance test CVE policy"
+			resources {
+				hosts = ["*"]
+				images = ["*", "foo/*"]
+				labels = ["*"]
+				containers = ["*"]
+			}
+			condition {
+				vulnerabilities {
+					id = 46
+					block = true
+					minimum_severity = 9
+				}
+				cves {
+					ids = ["CVE-2017-1234"]
+					effect = "alert"
+					only_fixed = true
+				}
 			}
-			"verbose" = "true"}
-		]
+			verbose = true
+		}
 	}`
 }
 
 func testAccCVEPolicy_UpdateConfig() string {
 	return `
 	resource "twistlock_machine_user" "test_user" {
-		"username" = "test-user"
-		"password" = "password"
-		"role" = "admin"
-		"auth_type" = "basic"
+		username = "test-user"
+		password = "password"
+		role = "admin"
+		auth_type = "basic"
 	}
 
 	resource "twistlock_cve_policy" "test_cve_policy" {
-		rules = [
-			{"owner" = "test_user"
-			 "name" = "Twistlock acceptance test CVE policy"
-			 "resources" {
-			 	"hosts" = ["foo/*"]
-			 	"images" = ["*"]
-			 	"labels" = ["*"]
-			 	"containers" = ["*"]
-			 }
-			 "condition" = {
-			 	"vulnerabilities" = [
-					{"id" = 46, "block" = true, "minimum_severity" = 7},
-					{"id" = 413, "block" = false, "minimum_severity" = 9}
-			 	]
-				"cves" = {
-				 	"ids" = ["CVE-2017-1234", "CVE-2017-2308"]
-				 	"effect" = "ignore"
-				 	"only_fixed" = false
-			 	}
+		rules {
+			owner = "test_user"
+			name = "Twistlock acceptance test CVE policy"
+			resources {
+				hosts = ["foo/*"]
+				images = ["*"]
+				labels = ["*"]
+				containers = ["*"]
+			}
+			condition {
+				vulnerabilities {
+					id = 46
+					block = true
+					minimum_severity = 7
+				}
+				vulnerabilities {
+					id = 413
+					block = false
+					minimum_severity = 9
+				}
+				cves {
+					ids = ["CVE-2017-1234", "CVE-2017-2308"]
+					effect = "ignore"
+					only_fixed = false
+				}
 			}
-			"verbose" = "true"
-			"block_message" = "Not permitted"}
-		]
+			verbose = true
+			block_message = "Not permitted"
+		}
 	}`
 }
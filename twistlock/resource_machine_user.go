@@ -3,7 +3,8 @@ package twistlock
 import (
 	"github.com/hashicorp/terraform/helper/schema"
 
-	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/model"
+	"github.com/circleci/terraform-provider-twistlock/password"
 )
 
 func resourceMachineUser() *schema.Resource {
@@ -11,37 +12,126 @@ func resourceMachineUser() *schema.Resource {
 		Create: resourceMachineUserCreate,
 		Read:   resourceUserRead,
 		Update: resourceMachineUserUpdate,
-		Delete: resourceUserDelete,
+		Delete: resourceMachineUserDelete,
 		Exists: resourceUserExists,
+		Importer: &schema.ResourceImporter{
+			State: resourceUserImport,
+		},
+
+		Timeouts:      defaultTimeouts(),
+		CustomizeDiff: allDiffs(authorize(model.PermissionManageUsers), validateRoleExists),
+
+		// Version 1 stores a hash of the password instead of the password
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceMachineUserV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: upgradeMachineUserStateV0,
+			},
+		},
 
+		Schema: map[string]*schema.Schema{
+			"username": {Type: schema.TypeString, Required: true},
+			"password": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Sensitive:        true,
+				DiffSuppressFunc: suppressHashedPassword,
+				Description:      "The user's password. Only a salted hash of it is stored in the state",
+			},
+			"password_version": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of times the provider has set the user's password",
+			},
+			"role":      {Type: schema.TypeString, Required: true, ValidateFunc: validateUserRole},
+			"auth_type": {Type: schema.TypeString, Required: true, ValidateFunc: validateUserAuthType},
+			"project":   projectSchema(),
+		},
+	}
+}
+
+// resourceMachineUserV0 is twistlock_machine_user before version 1, which
+// stored the password in the state.
+func resourceMachineUserV0() *schema.Resource {
+	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 			"username":  {Type: schema.TypeString, Required: true},
 			"password":  {Type: schema.TypeString, Required: true, Sensitive: true},
 			"role":      {Type: schema.TypeString, Required: true},
 			"auth_type": {Type: schema.TypeString, Required: true},
+			"project":   projectSchema(),
 		},
 	}
 }
 
+// upgradeMachineUserStateV0 replaces the password in a version 0 state with
+// its hash.
+func upgradeMachineUserStateV0(rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+	plaintext, _ := rawState["password"].(string)
+	if plaintext == "" {
+		return rawState, nil
+	}
+
+	hash, err := password.Hash(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	rawState["password"] = hash
+	rawState["password_version"] = 1
+	return rawState, nil
+}
+
+// suppressHashedPassword hides the difference between the password in the
+// configuration and the hash of the same password in the state.
+func suppressHashedPassword(_, old, new string, _ *schema.ResourceData) bool {
+	return password.Matches(old, new)
+}
+
+// setPassword records the hash of the password just set on the Console.
+func setPassword(d *schema.ResourceData, plaintext string) error {
+	hash, err := password.Hash(plaintext)
+	if err != nil {
+		return err
+	}
+	d.Set("password", hash)
+	d.Set("password_version", d.Get("password_version").(int)+1)
+	return nil
+}
+
 func resourceMachineUserCreate(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_machine_user", schema.TimeoutCreate)
+	defer cancel()
 
-	u := userFromResource(d)
+	u, err := userFromResource(d)
+	if err != nil {
+		return err
+	}
+	if err := checkUserRole(ctx, client, u.Role); err != nil {
+		return err
+	}
 	u.Password = d.Get("password").(string)
-	user, err := client.CreateUser(u)
+	user, err := client.CreateUser(ctx, u)
 
 	if err != nil {
 		return err
 	}
 
 	d.SetId(user.ID)
-	return nil
+	return setPassword(d, u.Password)
 }
 
 func resourceMachineUserUpdate(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_machine_user", schema.TimeoutUpdate)
+	defer cancel()
 	needsUpdate := false
-	userUpdate := userFromResource(d)
+	userUpdate, err := userFromResource(d)
+	if err != nil {
+		return err
+	}
 	// Prevent accidental password changes by ensuring this field is blank
 	userUpdate.Password = ""
 
@@ -50,6 +140,9 @@ func resourceMachineUserUpdate(d *schema.ResourceData, m interface{}) error {
 	}
 
 	if d.HasChange("role") {
+		if err := checkUserRole(ctx, client, userUpdate.Role); err != nil {
+			return err
+		}
 		needsUpdate = true
 	}
 	if d.HasChange("auth_type") {
@@ -61,11 +154,21 @@ func resourceMachineUserUpdate(d *schema.ResourceData, m interface{}) error {
 	}
 
 	if needsUpdate {
-		_, err := client.UpdateUser(userUpdate)
+		_, err := client.UpdateUser(ctx, userUpdate)
 		if err != nil {
 			return err
 		}
 	}
 
+	if userUpdate.Password != "" {
+		if err := setPassword(d, userUpdate.Password); err != nil {
+			return err
+		}
+	}
+
 	return resourceUserRead(d, m)
 }
+
+func resourceMachineUserDelete(d *schema.ResourceData, m interface{}) error {
+	return deleteUser(d, m, "twistlock_machine_user")
+}
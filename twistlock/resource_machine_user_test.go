@@ -1,22 +1,26 @@
 package twistlock
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"testing"
 
 	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
 	"github.com/circleci/terraform-provider-twistlock/model"
+	"github.com/circleci/terraform-provider-twistlock/password"
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAccMachineUser(t *testing.T) {
 	username := acctest.RandString(8)
 	password := acctest.RandString(10)
 
-	resource.Test(t, resource.TestCase{
+	testResource(t, resource.TestCase{
 		PreCheck: func() {
 			testAccPreCheck(t)
 		},
@@ -27,7 +31,8 @@ func TestAccMachineUser(t *testing.T) {
 				Config: testAccMachineUser_BasicConfig(username, password, model.RoleUser, model.AuthTypeBasic),
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "username", username),
-					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "password", password),
+					testAccMachineUser_PasswordHash(password),
+					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "password_version", "1"),
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "role", string(model.RoleUser)),
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "auth_type", string(model.AuthTypeBasic)),
 				),
@@ -37,7 +42,8 @@ func TestAccMachineUser(t *testing.T) {
 				Config: testAccMachineUser_BasicConfig(username, password, model.RoleDefenderManager, model.AuthTypeBasic),
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "username", username),
-					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "password", password),
+					testAccMachineUser_PasswordHash(password),
+					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "password_version", "1"),
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "role", string(model.RoleDefenderManager)),
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "auth_type", string(model.AuthTypeBasic)),
 				),
@@ -47,11 +53,25 @@ func TestAccMachineUser(t *testing.T) {
 				Config: testAccMachineUser_BasicConfig(username, password+"new", model.RoleDefenderManager, model.AuthTypeBasic),
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "username", username),
-					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "password", password+"new"),
+					testAccMachineUser_PasswordHash(password+"new"),
+					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "password_version", "2"),
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "role", string(model.RoleDefenderManager)),
 					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "auth_type", string(model.AuthTypeBasic)),
 				),
 			},
+			// The hash in the state matches the configured password
+			resource.TestStep{
+				Config:   testAccMachineUser_BasicConfig(username, password+"new", model.RoleDefenderManager, model.AuthTypeBasic),
+				PlanOnly: true,
+			},
+			// Import by username, the password cannot be read back
+			resource.TestStep{
+				ResourceName:            "twistlock_machine_user.test_user",
+				ImportState:             true,
+				ImportStateId:           username,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password", "password_version"},
+			},
 		},
 	})
 }
@@ -65,7 +85,7 @@ func TestAccMachineUser_CannotMutateImmutableUserProperties(t *testing.T) {
 		t.Fatal("Could not compile username check regular expression")
 	}
 
-	resource.Test(t, resource.TestCase{
+	testResource(t, resource.TestCase{
 		PreCheck: func() {
 			testAccPreCheck(t)
 		},
@@ -74,12 +94,15 @@ func TestAccMachineUser_CannotMutateImmutableUserProperties(t *testing.T) {
 		Steps: []resource.TestStep{
 			resource.TestStep{
 				Config: testAccMachineUser_BasicConfig(username, password, model.RoleUser, model.AuthTypeBasic),
-				Check: CheckTerraformState("twistlock_machine_user.test_user", AttrMap{
-					"username":  AttrLeaf(username),
-					"password":  AttrLeaf(password),
-					"role":      AttrLeaf(model.RoleUser),
-					"auth_type": AttrLeaf(model.AuthTypeBasic),
-				}),
+				Check: resource.ComposeTestCheckFunc(
+					CheckTerraformState("twistlock_machine_user.test_user", AttrMap{
+						"username":         AttrLeaf(username),
+						"password_version": AttrLeaf("1"),
+						"role":             AttrLeaf(model.RoleUser),
+						"auth_type":        AttrLeaf(model.AuthTypeBasic),
+					}),
+					testAccMachineUser_PasswordHash(password),
+				),
 			},
 			resource.TestStep{
 				Config:      testAccMachineUser_BasicConfig(username+"new", password, model.RoleUser, model.AuthTypeBasic),
@@ -87,15 +110,127 @@ func TestAccMachineUser_CannotMutateImmutableUserProperties(t *testing.T) {
 			}}})
 }
 
+func TestMachineUser_CreateFailure(t *testing.T) {
+	console := testFakeConsole(t)
+	console.InjectFault(fakeconsole.ServerError("POST", "/users"))
+	defer console.ClearFaults()
+
+	testResource(t, resource.TestCase{
+		CheckDestroy: testAccMachineUserDestroy,
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccMachineUser_BasicConfig(acctest.RandString(8), acctest.RandString(10), model.RoleUser, model.AuthTypeBasic),
+				ExpectError: regexp.MustCompile("POST /api/v1/users returned 500"),
+			},
+		},
+	})
+}
+
+func TestMachineUser_UserDeletedOutsideTerraform(t *testing.T) {
+	console := testFakeConsole(t)
+	username := acctest.RandString(8)
+	password := acctest.RandString(10)
+
+	testResource(t, resource.TestCase{
+		CheckDestroy: testAccMachineUserDestroy,
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccMachineUser_BasicConfig(username, password, model.RoleUser, model.AuthTypeBasic),
+			},
+			// The user is recreated after being removed from the Console
+			resource.TestStep{
+				PreConfig: func() {
+					console.RemoveUser(username)
+				},
+				Config: testAccMachineUser_BasicConfig(username, password, model.RoleUser, model.AuthTypeBasic),
+				Check: func(*terraform.State) error {
+					if _, exists := console.User(username); !exists {
+						return fmt.Errorf("User %s was not recreated", username)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestMachineUser_DeleteMissingUser(t *testing.T) {
+	assert := assert.New(t)
+	console := testFakeConsole(t)
+	defer console.ClearFaults()
+
+	c, err := client.NewClient(client.Config{Username: console.Username, Password: console.Password, BaseURL: console.URL()})
+	if !assert.Nil(err) {
+		return
+	}
+
+	// The Console answers 500 for users that don't exist
+	d := resourceMachineUser().TestResourceData()
+	d.SetId("ghost")
+	d.Set("username", "ghost")
+	assert.Nil(resourceMachineUserDelete(d, c))
+	assert.Equal("", d.Id())
+
+	// A user that is still there after a failed delete is an error
+	username := acctest.RandString(8)
+	console.AddUser(model.User{Username: username, Role: model.RoleUser, AuthType: model.AuthTypeBasic}, "password")
+	defer console.RemoveUser(username)
+	console.InjectFault(fakeconsole.ServerError("DELETE", "/users/"+username))
+	d.SetId(username)
+	d.Set("username", username)
+	assert.Error(resourceMachineUserDelete(d, c))
+}
+
+func TestMachineUser_Project(t *testing.T) {
+	console := testFakeConsole(t)
+	console.AddProject("team-a")
+	username := acctest.RandString(8)
+
+	testResource(t, resource.TestCase{
+		CheckDestroy: testAccMachineUserDestroy,
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: fmt.Sprintf(`
+					resource "twistlock_machine_user" "test_user" {
+						username = "%s"
+						password = "password"
+						role = "user"
+						auth_type = "basic"
+						project = "team-a"
+					}`, username),
+				Check: func(*terraform.State) error {
+					if _, exists := console.ProjectUser("team-a", username); !exists {
+						return fmt.Errorf("User %s was not created in project team-a", username)
+					}
+					if _, exists := console.User(username); exists {
+						return fmt.Errorf("User %s was created in the master project", username)
+					}
+					return nil
+				},
+			},
+			resource.TestStep{
+				ResourceName:            "twistlock_machine_user.test_user",
+				ImportState:             true,
+				ImportStateId:           "team-a/" + username,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password", "password_version"},
+			},
+		},
+	})
+}
+
 func testAccMachineUserDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(client.Client)
+	client := testAccProvider.Meta().(model.Console)
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "twistlock_machine_user" {
 			continue
 		}
 
-		_, found, err := client.ReadUser(rs.Primary.ID)
+		_, found, err := client.ReadUser(context.Background(), rs.Primary.ID)
 		if found && err == nil {
 			return fmt.Errorf("User still exists")
 		}
@@ -104,12 +239,47 @@ func testAccMachineUserDestroy(s *terraform.State) error {
 	return nil
 }
 
+// testAccMachineUser_PasswordHash checks that the state holds a hash of
+// `plaintext` rather than the password itself.
+func testAccMachineUser_PasswordHash(plaintext string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources["twistlock_machine_user.test_user"]
+		if !ok {
+			return fmt.Errorf("Could not find twistlock_machine_user.test_user")
+		}
+
+		hash := rs.Primary.Attributes["password"]
+		if !password.Matches(hash, plaintext) {
+			return fmt.Errorf("Expected a hash of the password in the state, got %q", hash)
+		}
+		return nil
+	}
+}
+
+func TestUpgradeMachineUserStateV0(t *testing.T) {
+	assert := assert.New(t)
+
+	state, err := upgradeMachineUserStateV0(map[string]interface{}{
+		"id":       "bob",
+		"username": "bob",
+		"password": "hunter2",
+	}, nil)
+	assert.Nil(err)
+	assert.True(password.Matches(state["password"].(string), "hunter2"))
+	assert.Equal(1, state["password_version"])
+	assert.Equal("bob", state["username"])
+
+	state, err = upgradeMachineUserStateV0(map[string]interface{}{"id": "bob", "password": ""}, nil)
+	assert.Nil(err)
+	assert.Equal("", state["password"], "imported users have no password to hash")
+}
+
 func testAccMachineUser_BasicConfig(username, password string, role model.UserRole, auth model.UserAuthType) string {
 	return fmt.Sprintf(`
 		resource "twistlock_machine_user" "test_user" {
-			"username" = "%s"
-			"password" = "%s"
-			"role" = "%s"
-			"auth_type" = "%s"
+			username = "%s"
+			password = "%s"
+			role = "%s"
+			auth_type = "%s"
 		  }`, username, password, role, auth)
 }
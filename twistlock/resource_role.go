@@ -0,0 +1,180 @@
+package twistlock
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/schema"
+
+	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+func resourceRole() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRoleCreate,
+		Read:   resourceRoleRead,
+		Update: resourceRoleUpdate,
+		Delete: resourceRoleDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceRoleImport,
+		},
+
+		Timeouts:      defaultTimeouts(),
+		CustomizeDiff: allDiffs(authorize(model.PermissionManageRoles), requireCustomRoles),
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateCustomRoleName,
+				Description:  "Name users refer to the role by in their `role`",
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"permission": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Access to one area of the Console, read-only unless read_write is set",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Area of the Console, e.g. policies, defenders or monitoring",
+						},
+						"read_write": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+					},
+				},
+			},
+			"project": projectSchema(),
+		},
+	}
+}
+
+// requireCustomRoles fails plans for roles on Consoles too old to have custom
+// roles.
+func requireCustomRoles(_ *schema.ResourceDiff, m interface{}) error {
+	return m.(model.Console).Version().Require("twistlock_role", model.CustomRolesMajor, model.CustomRolesMinor)
+}
+
+func roleFromResource(d *schema.ResourceData) *model.Role {
+	perms := d.Get("permission").(*schema.Set).List()
+	permissions := make([]model.RolePermission, len(perms))
+	for i, p := range perms {
+		perm := p.(map[string]interface{})
+		permissions[i] = model.RolePermission{
+			Name:      perm["name"].(string),
+			ReadWrite: perm["read_write"].(bool),
+		}
+	}
+
+	return &model.Role{
+		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+		Permissions: permissions,
+	}
+}
+
+func resourceRoleCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_role", schema.TimeoutCreate)
+	defer cancel()
+
+	role, err := client.CreateRole(ctx, roleFromResource(d))
+	if err != nil {
+		return err
+	}
+
+	d.SetId(role.Name)
+	return resourceRoleRead(d, m)
+}
+
+func resourceRoleRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(model.Console)
+	ctx, cancel := operationContext(d, schema.TimeoutRead)
+	defer cancel()
+
+	role, found, err := client.ReadRole(ctx, d.Id())
+	if err != nil {
+		return err
+	}
+	if !found {
+		// Tell terraform the role has been deleted
+		d.SetId("")
+		return nil
+	}
+
+	permissions := make([]interface{}, len(role.Permissions))
+	for i, p := range role.Permissions {
+		permissions[i] = map[string]interface{}{
+			"name":       p.Name,
+			"read_write": p.ReadWrite,
+		}
+	}
+
+	d.Set("name", role.Name)
+	d.Set("description", role.Description)
+	d.Set("permission", permissions)
+
+	return nil
+}
+
+func resourceRoleUpdate(d *schema.ResourceData, m interface{}) error {
+	if d.HasChange("description") || d.HasChange("permission") {
+		ctx, cancel := changeContext(d, "twistlock_role", schema.TimeoutUpdate)
+		defer cancel()
+
+		if _, err := m.(model.Console).UpdateRole(ctx, roleFromResource(d)); err != nil {
+			return err
+		}
+	}
+
+	return resourceRoleRead(d, m)
+}
+
+func resourceRoleDelete(d *schema.ResourceData, m interface{}) error {
+	c := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_role", schema.TimeoutDelete)
+	defer cancel()
+
+	err := c.DeleteRole(ctx, d.Id())
+
+	// A role that was already removed from the Console is as good as deleted
+	if err != nil && !client.IsNotFound(err) {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// resourceRoleImport accepts a custom role's name as the import ID,
+// optionally prefixed with `<project>/`. Built-in roles can't be imported.
+func resourceRoleImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	client := m.(model.Console)
+	name := importProject(d)
+	ctx, cancel := operationContext(d, schema.TimeoutRead)
+	defer cancel()
+
+	role, found, err := client.ReadRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("Cannot import Twistlock role '%s': no role has this name", d.Id())
+	}
+	if role.System {
+		return nil, fmt.Errorf("Cannot import Twistlock role '%s': built-in roles cannot be managed", d.Id())
+	}
+
+	d.SetId(role.Name)
+	return []*schema.ResourceData{d}, nil
+}
@@ -0,0 +1,148 @@
+package twistlock
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+	"github.com/circleci/terraform-provider-twistlock/model"
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccRole(t *testing.T) {
+	name := "tf-" + acctest.RandString(8)
+	username := acctest.RandString(8)
+
+	testResource(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		CheckDestroy: resource.ComposeTestCheckFunc(testAccMachineUserDestroy, testAccRoleDestroy),
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			// The user's role is created first
+			resource.TestStep{
+				Config: testAccRole_Config(name, username, "false"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("twistlock_role.test_role", "name", name),
+					resource.TestCheckResourceAttr("twistlock_role.test_role", "description", "Edits policies"),
+					resource.TestCheckResourceAttr("twistlock_role.test_role", "permission.#", "2"),
+					resource.TestCheckResourceAttr("twistlock_machine_user.test_user", "role", name),
+				),
+			},
+			// Update permissions
+			resource.TestStep{
+				Config: testAccRole_Config(name, username, "true"),
+				Check: func(*terraform.State) error {
+					role, found, err := testAccProvider.Meta().(model.Console).ReadRole(context.Background(), name)
+					if err != nil {
+						return err
+					}
+					if !found || !role.Can(model.PermissionManageUsers) {
+						return fmt.Errorf("Role %s was not given read-write access to users: %+v", name, role)
+					}
+					return nil
+				},
+			},
+			resource.TestStep{
+				ResourceName:      "twistlock_role.test_role",
+				ImportState:       true,
+				ImportStateId:     name,
+				ImportStateVerify: true,
+			},
+			resource.TestStep{
+				ResourceName:  "twistlock_role.test_role",
+				ImportState:   true,
+				ImportStateId: "admin",
+				ExpectError:   regexp.MustCompile("built-in roles cannot be managed"),
+			},
+		},
+	})
+}
+
+func TestAccRole_BuiltInName(t *testing.T) {
+	testResource(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: `
+					resource "twistlock_role" "test_role" {
+						name = "admin"
+					}`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("admin is a built-in role"),
+			},
+		},
+	})
+}
+
+func testAccRoleDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(model.Console)
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "twistlock_role" {
+			continue
+		}
+
+		_, found, err := client.ReadRole(context.Background(), rs.Primary.ID)
+		if found && err == nil {
+			return fmt.Errorf("Role still exists")
+		}
+	}
+
+	return nil
+}
+
+func testAccRole_Config(name, username, usersReadWrite string) string {
+	return fmt.Sprintf(`
+		resource "twistlock_role" "test_role" {
+			name = "%s"
+			description = "Edits policies"
+
+			permission {
+				name = "policies"
+				read_write = true
+			}
+
+			permission {
+				name = "user"
+				read_write = %s
+			}
+		}
+
+		resource "twistlock_machine_user" "test_user" {
+			username = "%s"
+			password = "password"
+			role = twistlock_role.test_role.id
+			auth_type = "basic"
+		}`, name, usersReadWrite, username)
+}
+
+func TestAccRole_UnsupportedConsole(t *testing.T) {
+	console := testFakeConsole(t)
+	console.SetVersion("19.03.311")
+	defer console.SetVersion(fakeconsole.DefaultVersion)
+
+	testResource(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: `
+					resource "twistlock_role" "test_role" {
+						name = "policy-editor"
+					}`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("twistlock_role is unsupported on Console 19.03.311, it requires 19.07 or later"),
+			},
+			resource.TestStep{
+				Config:      testAccMachineUser_BasicConfig(acctest.RandString(8), "password", "policy-editor", "basic"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("Custom role policy-editor is unsupported on Console 19.03.311, it requires 19.07 or later"),
+			},
+		},
+	})
+}
@@ -1,8 +1,13 @@
 package twistlock
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/hashicorp/terraform/helper/didyoumean"
 	"github.com/hashicorp/terraform/helper/encryption"
 	"github.com/hashicorp/terraform/helper/schema"
 
@@ -20,67 +25,384 @@ func resourceUser() *schema.Resource {
 		Update: resourceUserUpdate,
 		Delete: resourceUserDelete,
 		Exists: resourceUserExists,
+		Importer: &schema.ResourceImporter{
+			State: resourceUserImport,
+		},
+
+		Timeouts:      defaultTimeouts(),
+		CustomizeDiff: allDiffs(authorize(model.PermissionManageUsers), validateRoleExists, validateUserAuth, validatePasswordPolicy, planPasswordRotation),
 
 		Schema: map[string]*schema.Schema{
-			"username":           {Type: schema.TypeString, Required: true},
-			"pgp_key":            {Type: schema.TypeString, Required: true},
-			"role":               {Type: schema.TypeString, Required: true},
-			"auth_type":          {Type: schema.TypeString, Required: true},
+			"username": {Type: schema.TypeString, Required: true},
+			"pgp_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PGP key to encrypt the generated password with, required when auth_type is basic and rejected otherwise",
+			},
+			"role":               {Type: schema.TypeString, Required: true, ValidateFunc: validateUserRole},
+			"auth_type":          {Type: schema.TypeString, Required: true, ValidateFunc: validateUserAuthType},
 			"encrypted_password": {Type: schema.TypeString, Computed: true},
 			"key_fingerprint":    {Type: schema.TypeString, Computed: true},
+			"password_policy":    passwordPolicySchema(),
+			"rotation_days": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validateNonNegativeInt,
+				Description:  "Generate a new password on the first apply this many days after the last one, 0 never does",
+			},
+			"keepers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary values that generate a new password whenever they change",
+			},
+			"password_rotated_at": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "When the current password was generated, in RFC 3339 format",
+			},
+			"project": projectSchema(),
 		},
 	}
 }
 
-func userFromResource(d *schema.ResourceData) *model.User {
+func userFromResource(d *schema.ResourceData) (*model.User, error) {
 	var role model.UserRole
 	var auth model.UserAuthType
 
-	role.UnmarshalText([]byte(d.Get("role").(string)))
-	auth.UnmarshalText([]byte(d.Get("auth_type").(string)))
+	if err := role.UnmarshalText([]byte(d.Get("role").(string))); err != nil {
+		return nil, err
+	}
+	if err := auth.UnmarshalText([]byte(d.Get("auth_type").(string))); err != nil {
+		return nil, err
+	}
 
 	return &model.User{
 		Username: d.Get("username").(string),
 		Role:     role,
 		AuthType: auth,
+	}, nil
+}
+
+// checkUserRole returns an error when `role` is neither a built-in role nor a
+// custom role on the Console, suggesting the closest role that exists. When
+// the provider's user may not list roles only names close to a built-in role
+// fail, other custom roles are left to the Console.
+func checkUserRole(ctx context.Context, c model.Console, role model.UserRole) error {
+	if role.BuiltIn() {
+		return nil
+	}
+
+	// Consoles without custom roles only have the built-in ones
+	if err := c.Version().Require("Custom role "+string(role), model.CustomRolesMajor, model.CustomRolesMinor); err != nil {
+		if suggestion := builtInRoleSuggestion(role); suggestion != "" {
+			return fmt.Errorf("Role %s is not a built-in role. Did you mean %q?", role, suggestion)
+		}
+		return err
+	}
+
+	roles, err := c.ListRoles(ctx)
+	if client.IsForbidden(err) {
+		if suggestion := builtInRoleSuggestion(role); suggestion != "" {
+			return fmt.Errorf("Role %s is not a built-in role and the Twistlock Console's custom roles could not be listed to check it. Did you mean %q?", role, suggestion)
+		}
+		log.Printf("[WARN] Could not verify that custom role %s exists: %s", role, err)
+		return nil
+	}
+	if err != nil {
+		return err
 	}
+
+	names := make([]string, len(roles))
+	for i, r := range roles {
+		if r.Name == string(role) {
+			return nil
+		}
+		names[i] = r.Name
+	}
+
+	msg := fmt.Sprintf("Role %s does not exist on the Twistlock Console", role)
+	if suggestion := didyoumean.NameSuggestion(string(role), names); suggestion != "" {
+		msg += fmt.Sprintf(". Did you mean %q?", suggestion)
+	}
+	return errors.New(msg)
+}
+
+// validateRoleExists fails plans giving a user a role that doesn't exist on
+// the Console. Roles only known at apply time, e.g. the `id` of a
+// twistlock_role created in the same run, are checked when the user is
+// created or updated instead.
+func validateRoleExists(d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("role") || (d.Id() != "" && !d.HasChange("role")) {
+		return nil
+	}
+
+	ctx, cancel := diffContext(d)
+	defer cancel()
+	return checkUserRole(ctx, m.(model.Console), model.UserRole(d.Get("role").(string)))
+}
+
+// builtInRoleSuggestion returns the built-in role closest to `role`, or ""
+// when none is close.
+func builtInRoleSuggestion(role model.UserRole) string {
+	builtIn := make([]string, len(model.UserRoles))
+	for i, r := range model.UserRoles {
+		builtIn[i] = string(r)
+	}
+	return didyoumean.NameSuggestion(string(role), builtIn)
 }
 
 func resourceUserCreate(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_user", schema.TimeoutCreate)
+	defer cancel()
 
-	encryptionKey, err := encryption.RetrieveGPGKey(d.Get("pgp_key").(string))
+	u, err := userFromResource(d)
 	if err != nil {
 		return err
 	}
+	if err := checkUserRole(ctx, client, u.Role); err != nil {
+		return err
+	}
+
+	// Users logging in through an identity provider have no password
+	var encrypted *encryptedPassword
+	if userHasPassword(d) {
+		password, generated, err := generatePassword(d)
+		if err != nil {
+			return err
+		}
+		u.Password = password
+		encrypted = &generated
+	}
+
+	user, err := client.CreateUser(ctx, u)
 
-	password, err := password.RandomString(30)
 	if err != nil {
 		return err
 	}
 
+	d.SetId(user.ID)
+	encrypted.set(d)
+	return nil
+}
+
+// encryptedPassword is a generated password encrypted with the user's PGP
+// key, as stored in the resource's state.
+type encryptedPassword struct {
+	value       string
+	fingerprint string
+	rotatedAt   time.Time
+}
+
+// set stores p in `d`, a nil p clears the password of a user without one.
+func (p *encryptedPassword) set(d *schema.ResourceData) {
+	if p == nil {
+		for _, key := range passwordKeys {
+			d.Set(key, "")
+		}
+		return
+	}
+
+	d.Set("encrypted_password", p.value)
+	d.Set("key_fingerprint", p.fingerprint)
+	d.Set("password_rotated_at", p.rotatedAt.Format(time.RFC3339))
+}
+
+// passwordKeys are the computed attributes describing the generated password.
+var passwordKeys = []string{"encrypted_password", "key_fingerprint", "password_rotated_at"}
+
+// userHasPassword reports whether the user logs in with a password the
+// provider generates, rather than through an identity provider.
+func userHasPassword(d resourceGetter) bool {
+	return model.UserAuthType(d.Get("auth_type").(string)).HasPassword()
+}
+
+// validateUserAuth fails plans that configure a pgp_key for users without a
+// password, or none for users with one.
+func validateUserAuth(d *schema.ResourceDiff, _ interface{}) error {
+	if !d.NewValueKnown("auth_type") || !d.NewValueKnown("pgp_key") {
+		return nil
+	}
+
+	var auth model.UserAuthType
+	if err := auth.UnmarshalText([]byte(d.Get("auth_type").(string))); err != nil {
+		return nil
+	}
+
+	pgpKey := d.Get("pgp_key").(string)
+	switch {
+	case auth.HasPassword() && pgpKey == "":
+		return fmt.Errorf("pgp_key is required for users with auth_type %s, it encrypts their generated password", auth)
+	case !auth.HasPassword() && pgpKey != "":
+		return fmt.Errorf("pgp_key doesn't apply to users with auth_type %s, who log in through an identity provider without a password", auth)
+	}
+	return nil
+}
+
+// generatePassword generates a password following the resource's
+// password_policy and encrypts it with its pgp_key.
+func generatePassword(d *schema.ResourceData) (string, encryptedPassword, error) {
+	encryptionKey, err := encryption.RetrieveGPGKey(d.Get("pgp_key").(string))
+	if err != nil {
+		return "", encryptedPassword{}, err
+	}
+
+	password, err := passwordPolicyFromResource(d).Generate()
+	if err != nil {
+		return "", encryptedPassword{}, err
+	}
+
 	fingerprint, encrypted, err := encryption.EncryptValue(encryptionKey, password, "Generated Password")
 	if err != nil {
-		return err
+		return "", encryptedPassword{}, err
 	}
 
-	u := userFromResource(d)
-	u.Password = password
-	user, err := client.CreateUser(u)
+	return password, encryptedPassword{
+		value:       encrypted,
+		fingerprint: fingerprint,
+		rotatedAt:   time.Now().UTC(),
+	}, nil
+}
 
+// passwordRotationDue reports whether a password generated at `rotatedAt`
+// is at least `days` days old at `now`. A password of unknown age, e.g. of
+// an imported user, is always due.
+func passwordRotationDue(rotatedAt string, days int, now time.Time) bool {
+	if days <= 0 {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, rotatedAt)
 	if err != nil {
-		return err
+		return true
 	}
+	return !now.Before(t.Add(time.Duration(days) * 24 * time.Hour))
+}
 
-	d.SetId(user.ID)
-	d.Set("encrypted_password", encrypted)
-	d.Set("key_fingerprint", fingerprint)
+// needsPasswordRotation reports whether the user's password is to be
+// replaced, because its keepers changed, it is older than rotation_days or
+// the user just switched to basic auth.
+func needsPasswordRotation(d interface {
+	resourceGetter
+	HasChange(key string) bool
+}) bool {
+	if !userHasPassword(d) {
+		return false
+	}
+	if d.HasChange("keepers") || d.HasChange("auth_type") {
+		return true
+	}
+	return passwordRotationDue(d.Get("password_rotated_at").(string), d.Get("rotation_days").(int), time.Now())
+}
+
+// planPasswordRotation shows a new encrypted_password in plans that rotate
+// the user's password, so that Terraform calls Update to rotate it, and
+// none in plans that switch the user to an identity provider.
+func planPasswordRotation(d *schema.ResourceDiff, _ interface{}) error {
+	if d.Id() == "" || !d.NewValueKnown("auth_type") {
+		return nil
+	}
+
+	switch {
+	case needsPasswordRotation(d):
+		for _, key := range passwordKeys {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+	case d.HasChange("auth_type") && !userHasPassword(d):
+		for _, key := range passwordKeys {
+			if err := d.SetNew(key, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// passwordPolicySchema is the `password_policy` block of twistlock_user,
+// which controls the passwords the provider generates.
+func passwordPolicySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "How to generate the user's password. Changes apply to the next password generated",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"length": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      int(password.DefaultPolicy.Length),
+					ValidateFunc: validateMinInt(password.MinLength),
+				},
+				"charset": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     password.DefaultCharset,
+					Description: "Characters to choose from",
+				},
+				"exclude": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     "",
+					Description: "Characters never to use, e.g. quotes, backslashes and spaces",
+				},
+				"min_upper":  passwordMinimumSchema("uppercase letters"),
+				"min_lower":  passwordMinimumSchema("lowercase letters"),
+				"min_digit":  passwordMinimumSchema("digits"),
+				"min_symbol": passwordMinimumSchema("characters other than letters and digits"),
+			},
+		},
+	}
+}
+
+func passwordMinimumSchema(class string) *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Default:      0,
+		ValidateFunc: validateNonNegativeInt,
+		Description:  "Fewest " + class + " the password may contain",
+	}
+}
+
+// passwordPolicyFromResource returns the configured password policy, or
+// password.DefaultPolicy without a `password_policy` block.
+func passwordPolicyFromResource(d resourceGetter) password.Policy {
+	policies := d.Get("password_policy").([]interface{})
+	if len(policies) == 0 || policies[0] == nil {
+		return password.DefaultPolicy
+	}
+
+	p := policies[0].(map[string]interface{})
+	return password.Policy{
+		Length:    uint(p["length"].(int)),
+		Charset:   p["charset"].(string),
+		Exclude:   p["exclude"].(string),
+		MinUpper:  uint(p["min_upper"].(int)),
+		MinLower:  uint(p["min_lower"].(int)),
+		MinDigit:  uint(p["min_digit"].(int)),
+		MinSymbol: uint(p["min_symbol"].(int)),
+	}
+}
+
+// validatePasswordPolicy fails plans whose password_policy no password can
+// satisfy, e.g. because the minimums add up to more than the length.
+func validatePasswordPolicy(d *schema.ResourceDiff, _ interface{}) error {
+	if err := passwordPolicyFromResource(d).Validate(); err != nil {
+		return fmt.Errorf("Invalid password_policy: %s", err)
+	}
 	return nil
 }
 
 func resourceUserRead(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
-	u, found, err := client.ReadUser(d.Id())
+	client := m.(model.Console)
+	ctx, cancel := operationContext(d, schema.TimeoutRead)
+	defer cancel()
+
+	u, found, err := client.ReadUser(ctx, d.Id())
 
 	if err != nil {
 		return err
@@ -99,9 +421,14 @@ func resourceUserRead(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceUserUpdate(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
+	client := m.(model.Console)
+	ctx, cancel := changeContext(d, "twistlock_user", schema.TimeoutUpdate)
+	defer cancel()
 	needsUpdate := false
-	userUpdate := userFromResource(d)
+	userUpdate, err := userFromResource(d)
+	if err != nil {
+		return err
+	}
 	// Prevent accidental password changes by ensuring this field is blank
 	userUpdate.Password = ""
 
@@ -110,28 +437,63 @@ func resourceUserUpdate(d *schema.ResourceData, m interface{}) error {
 	}
 
 	if d.HasChange("role") {
+		if err := checkUserRole(ctx, client, userUpdate.Role); err != nil {
+			return err
+		}
 		needsUpdate = true
 	}
 	if d.HasChange("auth_type") {
 		needsUpdate = true
 	}
 
+	var rotated *encryptedPassword
+	if needsPasswordRotation(d) {
+		password, encrypted, err := generatePassword(d)
+		if err != nil {
+			return err
+		}
+		needsUpdate = true
+		userUpdate.Password = password
+		rotated = &encrypted
+	}
+
 	if needsUpdate {
-		_, err := client.UpdateUser(userUpdate)
+		_, err := client.UpdateUser(ctx, userUpdate)
 		if err != nil {
 			return err
 		}
 	}
 
+	if rotated != nil || !userHasPassword(d) {
+		rotated.set(d)
+	}
+
 	return resourceUserRead(d, m)
 }
 
 func resourceUserDelete(d *schema.ResourceData, m interface{}) error {
-	client := m.(client.Client)
-	err := client.DeleteUser(userFromResource(d))
+	return deleteUser(d, m, "twistlock_user")
+}
 
+// deleteUser deletes the user of `d`, a resource of type `resourceType`.
+func deleteUser(d *schema.ResourceData, m interface{}, resourceType string) error {
+	c := m.(model.Console)
+	ctx, cancel := changeContext(d, resourceType, schema.TimeoutDelete)
+	defer cancel()
+
+	// Only the username is needed, which lets users whose role is no longer
+	// valid still be deleted
+	username := d.Get("username").(string)
+	err := c.DeleteUser(ctx, &model.User{Username: username})
+
+	// A user that was already removed from the Console is as good as deleted.
+	// The Console answers 500 rather than 404 for users that don't exist, so
+	// look the user up to tell that apart from a failed delete.
 	if err != nil {
-		return err
+		_, found, readErr := c.ReadUserByName(ctx, username)
+		if readErr != nil || found {
+			return err
+		}
 	}
 
 	// Setting a blank ID is not strictly necessary, including for completeness
@@ -140,8 +502,40 @@ func resourceUserDelete(d *schema.ResourceData, m interface{}) error {
 }
 
 func resourceUserExists(d *schema.ResourceData, m interface{}) (bool, error) {
-	client := m.(client.Client)
+	client := m.(model.Console)
+	ctx, cancel := operationContext(d, schema.TimeoutRead)
+	defer cancel()
 
-	_, found, err := client.ReadUser(d.Id())
+	_, found, err := client.ReadUser(ctx, d.Id())
 	return found, err
 }
+
+// resourceUserImport accepts either a user's `_id` or username as the import
+// ID, optionally prefixed with `<project>/`. The rest of the state is filled
+// in by resourceUserRead.
+//
+// Imported twistlock_user resources have no encrypted_password: the Console
+// never returns passwords, so there is nothing to encrypt.
+func resourceUserImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	client := m.(model.Console)
+	id := importProject(d)
+	ctx, cancel := operationContext(d, schema.TimeoutRead)
+	defer cancel()
+
+	u, found, err := client.ReadUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		u, found, err = client.ReadUserByName(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("Cannot import Twistlock user '%s': no user has this ID or username", d.Id())
+	}
+
+	d.SetId(u.ID)
+	return []*schema.ResourceData{d}, nil
+}
@@ -1,17 +1,19 @@
 package twistlock
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"regexp"
 	"testing"
+	"time"
 
-	"github.com/circleci/terraform-provider-twistlock/client"
 	"github.com/circleci/terraform-provider-twistlock/model"
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/hashicorp/vault/helper/pgpkeys"
+	"github.com/stretchr/testify/assert"
 )
 
 const terraformTestPublicKeyPath string = "testdata/test-gpg-keys/terraform.pub"
@@ -44,7 +46,7 @@ func init() {
 func TestAccUser(t *testing.T) {
 	username := acctest.RandString(8)
 
-	resource.Test(t, resource.TestCase{
+	testResource(t, resource.TestCase{
 		PreCheck: func() {
 			testAccPreCheck(t)
 		},
@@ -76,6 +78,21 @@ func TestAccUser(t *testing.T) {
 					testAccUser_GeneratedPassword,
 				),
 			},
+			// Import by ID
+			resource.TestStep{
+				ResourceName:            "twistlock_user.test_user",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"pgp_key", "encrypted_password", "key_fingerprint", "password_rotated_at", "rotation_days"},
+			},
+			// Import by username
+			resource.TestStep{
+				ResourceName:            "twistlock_user.test_user",
+				ImportState:             true,
+				ImportStateId:           username,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"pgp_key", "encrypted_password", "key_fingerprint", "password_rotated_at", "rotation_days"},
+			},
 		},
 	})
 }
@@ -88,7 +105,7 @@ func TestAccUser_CannotMutateImmutableUserProperties(t *testing.T) {
 		t.Fatalf("Could not compile username check regular expression")
 	}
 
-	resource.Test(t, resource.TestCase{
+	testResource(t, resource.TestCase{
 		PreCheck: func() {
 			testAccPreCheck(t)
 		},
@@ -112,14 +129,14 @@ func TestAccUser_CannotMutateImmutableUserProperties(t *testing.T) {
 }
 
 func testAccUserDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(client.Client)
+	client := testAccProvider.Meta().(model.Console)
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "twistlock_user" {
 			continue
 		}
 
-		_, found, err := client.ReadUser(rs.Primary.ID)
+		_, found, err := client.ReadUser(context.Background(), rs.Primary.ID)
 		if found && err == nil {
 			return fmt.Errorf("User still exists")
 		}
@@ -153,13 +170,214 @@ func testAccUser_GeneratedPassword(s *terraform.State) error {
 func testAccUser_BasicConfig(username, publicKeyFile string, role model.UserRole, auth model.UserAuthType) string {
 	return fmt.Sprintf(`
 		resource "twistlock_user" "test_user" {
-			"username" = "%s"
-			"pgp_key" = "${file("%s")}"
-			"role" = "%s"
-			"auth_type" = "%s"
+			username = "%s"
+			pgp_key = file("%s")
+			role = "%s"
+			auth_type = "%s"
 		}
 
-		output "password" {
-			value = "${twistlock_user.test_user.encrypted_password}"
+		output password {
+			value = twistlock_user.test_user.encrypted_password
 		}`, username, publicKeyFile, role, auth)
 }
+
+func TestAccUser_PasswordPolicy(t *testing.T) {
+	username := acctest.RandString(8)
+	config := func(policy string) string {
+		return fmt.Sprintf(`
+			resource "twistlock_user" "test_user" {
+				username = "%s"
+				pgp_key = file("testdata/test-gpg-keys/terraform.pub")
+				role = "user"
+				auth_type = "basic"
+
+				password_policy {
+					%s
+				}
+			}
+
+			output password {
+				value = twistlock_user.test_user.encrypted_password
+			}`, username, policy)
+	}
+
+	testResource(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		CheckDestroy: testAccUserDestroy,
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      config("length = 8\nmin_digit = 5\nmin_symbol = 4"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("Invalid password_policy: the minimum counts add up to 9 characters, more than the length of 8"),
+			},
+			resource.TestStep{
+				Config: config(`
+					length = 12
+					exclude = "\"\\ "
+					min_upper = 2
+					min_digit = 2
+					min_symbol = 2`),
+				Check: func(s *terraform.State) error {
+					passwordBytes, err := pgpkeys.DecryptBytes(s.RootModule().Outputs["password"].Value.(string), terraformTestPrivateKey)
+					if err != nil {
+						return err
+					}
+					password := passwordBytes.String()
+					if !regexp.MustCompile(`^[^"\\ ]{12}$`).MatchString(password) {
+						return fmt.Errorf("Password %q doesn't match the policy", password)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccUser_PasswordRotation(t *testing.T) {
+	username := acctest.RandString(8)
+	config := func(keeper string) string {
+		return fmt.Sprintf(`
+			resource "twistlock_user" "test_user" {
+				username = "%s"
+				pgp_key = file("testdata/test-gpg-keys/terraform.pub")
+				role = "user"
+				auth_type = "basic"
+				rotation_days = 90
+
+				keepers = {
+					rotated_by = "%s"
+				}
+			}
+
+			output password {
+				value = twistlock_user.test_user.encrypted_password
+			}`, username, keeper)
+	}
+
+	var passwords []string
+	recordPassword := func(s *terraform.State) error {
+		passwordBytes, err := pgpkeys.DecryptBytes(s.RootModule().Outputs["password"].Value.(string), terraformTestPrivateKey)
+		if err != nil {
+			return err
+		}
+		passwords = append(passwords, passwordBytes.String())
+		return nil
+	}
+
+	testResource(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		CheckDestroy: testAccUserDestroy,
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: config("alice"),
+				Check: resource.ComposeTestCheckFunc(
+					recordPassword,
+					resource.TestCheckResourceAttrSet("twistlock_user.test_user", "password_rotated_at"),
+				),
+			},
+			// Unchanged keepers within rotation_days keep the password
+			resource.TestStep{
+				Config:   config("alice"),
+				PlanOnly: true,
+			},
+			resource.TestStep{
+				Config: config("bob"),
+				Check: resource.ComposeTestCheckFunc(
+					recordPassword,
+					func(*terraform.State) error {
+						if passwords[0] == passwords[1] {
+							return fmt.Errorf("Password was not rotated")
+						}
+						if testConsole != nil && testConsole.UserPassword(username) != passwords[1] {
+							return fmt.Errorf("Console has a different password than encrypted_password")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestPasswordRotationDue(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2020, 5, 4, 12, 0, 0, 0, time.UTC)
+
+	assert.False(passwordRotationDue("2020-01-01T00:00:00Z", 0, now), "rotation_days = 0 never rotates")
+	assert.False(passwordRotationDue("2020-04-05T12:00:01Z", 30, now))
+	assert.True(passwordRotationDue("2020-04-04T12:00:00Z", 30, now))
+	assert.True(passwordRotationDue("", 30, now), "passwords of unknown age are due")
+}
+
+func TestAccUser_SSO(t *testing.T) {
+	username := acctest.RandString(8)
+	config := func(auth model.UserAuthType, pgpKey string) string {
+		return fmt.Sprintf(`
+			resource "twistlock_user" "test_user" {
+				username = "%s"
+				role = "user"
+				auth_type = "%s"
+				%s
+			}
+
+			output password {
+				value = twistlock_user.test_user.encrypted_password
+			}`, username, auth, pgpKey)
+	}
+	pgpKey := `pgp_key = file("testdata/test-gpg-keys/terraform.pub")`
+
+	testResource(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		CheckDestroy: testAccUserDestroy,
+		Providers:    testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      config(model.AuthTypeSAML, pgpKey),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("pgp_key doesn't apply to users with auth_type saml"),
+			},
+			resource.TestStep{
+				Config:      config(model.AuthTypeBasic, ""),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("pgp_key is required for users with auth_type basic"),
+			},
+			resource.TestStep{
+				Config: config(model.AuthTypeOIDC, ""),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("twistlock_user.test_user", "auth_type", string(model.AuthTypeOIDC)),
+					resource.TestCheckResourceAttr("twistlock_user.test_user", "encrypted_password", ""),
+					func(*terraform.State) error {
+						if testConsole != nil && testConsole.UserPassword(username) != "" {
+							return fmt.Errorf("A password was set for an OIDC user")
+						}
+						return nil
+					},
+				),
+			},
+			// Switching to basic auth generates a password
+			resource.TestStep{
+				Config: config(model.AuthTypeBasic, pgpKey),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("twistlock_user.test_user", "auth_type", string(model.AuthTypeBasic)),
+					testAccUser_GeneratedPassword,
+				),
+			},
+			resource.TestStep{
+				Config: config(model.AuthTypeLDAP, ""),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("twistlock_user.test_user", "auth_type", string(model.AuthTypeLDAP)),
+					resource.TestCheckResourceAttr("twistlock_user.test_user", "encrypted_password", ""),
+					resource.TestCheckResourceAttr("twistlock_user.test_user", "key_fingerprint", ""),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,151 @@
+package twistlock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/didyoumean"
+
+	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/model"
+)
+
+// validateDuration checks that a string attribute parses as a Go duration,
+// e.g. "30s" or "2m".
+func validateDuration(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("%q must be a duration such as \"30s\" or \"2m\", got %q", k, value))
+		return
+	}
+	if d < 0 {
+		errors = append(errors, fmt.Errorf("%q must not be negative, got %q", k, value))
+	}
+	return
+}
+
+// validateNonNegativeInt checks that an int attribute is zero or more.
+func validateNonNegativeInt(v interface{}, k string) (ws []string, errors []error) {
+	if v.(int) < 0 {
+		errors = append(errors, fmt.Errorf("%q must not be negative, got %d", k, v.(int)))
+	}
+	return
+}
+
+// validateMinInt returns a ValidateFunc checking that an int attribute is
+// at least `min`.
+func validateMinInt(min int) func(interface{}, string) ([]string, []error) {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+		if v.(int) < min {
+			errors = append(errors, fmt.Errorf("%q must be at least %d, got %d", k, min, v.(int)))
+		}
+		return
+	}
+}
+
+// validateNonNegativeFloat checks that a float attribute is zero or more.
+func validateNonNegativeFloat(v interface{}, k string) (ws []string, errors []error) {
+	if v.(float64) < 0 {
+		errors = append(errors, fmt.Errorf("%q must not be negative, got %g", k, v.(float64)))
+	}
+	return
+}
+
+// validateTLSVersion checks that a string attribute is a TLS version the
+// client understands.
+func validateTLSVersion(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := client.ParseTLSVersion(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%q: %s", k, err))
+	}
+	return
+}
+
+// validateOneOf returns a ValidateFunc checking that a string attribute is
+// one of `values`, suggesting the closest one when it isn't.
+func validateOneOf(values []string) func(interface{}, string) ([]string, []error) {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+		value := v.(string)
+		for _, allowed := range values {
+			if value == allowed {
+				return
+			}
+		}
+
+		msg := fmt.Sprintf("%q must be one of %s, got %q", k, strings.Join(values, ", "), value)
+		if suggestion := didyoumean.NameSuggestion(value, values); suggestion != "" {
+			msg += fmt.Sprintf(". Did you mean %q?", suggestion)
+		}
+		errors = append(errors, fmt.Errorf("%s", msg))
+		return
+	}
+}
+
+// validateUserRole checks that a string attribute names a role. Whether the
+// role exists is up to the Console, see validateRoleExists.
+func validateUserRole(v interface{}, k string) (ws []string, errors []error) {
+	if v.(string) == "" {
+		errors = append(errors, fmt.Errorf("%q must not be empty", k))
+	}
+	return
+}
+
+// validateCustomRoleName checks that a string attribute can name a custom
+// role.
+func validateCustomRoleName(v interface{}, k string) (ws []string, errors []error) {
+	name := v.(string)
+	switch {
+	case name == "":
+		errors = append(errors, fmt.Errorf("%q must not be empty", k))
+	case model.UserRole(name).BuiltIn():
+		errors = append(errors, fmt.Errorf("%q: %s is a built-in role, custom roles need another name", k, name))
+	case strings.ContainsAny(name, "/?#"):
+		errors = append(errors, fmt.Errorf("%q must not contain /, ? or #, got %q", k, name))
+	}
+	return
+}
+
+// validateUserAuthType checks that a string attribute is a
+// model.UserAuthType.
+var validateUserAuthType = func() func(interface{}, string) ([]string, []error) {
+	values := make([]string, len(model.UserAuthTypes))
+	for i, a := range model.UserAuthTypes {
+		values[i] = string(a)
+	}
+	return validateOneOf(values)
+}()
+
+// validateCVEEffect checks that a string attribute is a model.CVEEffect.
+var validateCVEEffect = func() func(interface{}, string) ([]string, []error) {
+	values := make([]string, len(model.CVEEffects))
+	for i, e := range model.CVEEffects {
+		values[i] = string(e)
+	}
+	return validateOneOf(values)
+}()
+
+// validateCVSSv3Severity checks that a float attribute is one of the
+// model.CVSSv3Severities, suggesting the one of the score's rating.
+func validateCVSSv3Severity(v interface{}, k string) (ws []string, errors []error) {
+	score := model.CVSSv3(v.(float64))
+	allowed := make([]string, len(model.CVSSv3Severities))
+	for i, s := range model.CVSSv3Severities {
+		if score == s {
+			return
+		}
+		allowed[i] = fmt.Sprintf("%g (%s)", float64(s), s.Rating())
+	}
+
+	msg := fmt.Sprintf("%q must be one of %s, got %g", k, strings.Join(allowed, ", "), float64(score))
+	if score >= 0 && score <= 10 {
+		for i := len(model.CVSSv3Severities) - 1; i >= 0; i-- {
+			if s := model.CVSSv3Severities[i]; score > s {
+				msg += fmt.Sprintf(". Did you mean %s, %g?", s.Rating(), float64(s))
+				break
+			}
+		}
+	}
+	errors = append(errors, fmt.Errorf("%s", msg))
+	return
+}
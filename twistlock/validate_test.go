@@ -0,0 +1,126 @@
+package twistlock
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/circleci/terraform-provider-twistlock/client"
+	"github.com/circleci/terraform-provider-twistlock/fakeconsole"
+)
+
+func TestValidateUserRole(t *testing.T) {
+	assert := assert.New(t)
+
+	ws, errors := validateUserRole("defenderManager", "role")
+	assert.Empty(ws)
+	assert.Empty(errors)
+
+	ws, errors = validateUserRole("policy-editor", "role")
+	assert.Empty(ws, "custom roles are checked against the Console")
+	assert.Empty(errors)
+
+	_, errors = validateUserRole("", "role")
+	if assert.Len(errors, 1) {
+		assert.EqualError(errors[0], `"role" must not be empty`)
+	}
+}
+
+func TestValidateCustomRoleName(t *testing.T) {
+	assert := assert.New(t)
+
+	_, errors := validateCustomRoleName("policy-editor", "name")
+	assert.Empty(errors)
+
+	_, errors = validateCustomRoleName("admin", "name")
+	if assert.Len(errors, 1) {
+		assert.EqualError(errors[0], `"name": admin is a built-in role, custom roles need another name`)
+	}
+
+	_, errors = validateCustomRoleName("team/editor", "name")
+	assert.Len(errors, 1)
+}
+
+func TestValidateCVEEffect(t *testing.T) {
+	assert := assert.New(t)
+
+	_, errors := validateCVEEffect("alert", "effect")
+	assert.Empty(errors)
+
+	_, errors = validateCVEEffect("blok", "effect")
+	if assert.Len(errors, 1) {
+		assert.EqualError(errors[0], `"effect" must be one of ignore, alert, block, got "blok". Did you mean "block"?`)
+	}
+}
+
+func TestValidateAuthMethod(t *testing.T) {
+	assert := assert.New(t)
+	validate := Provider().Schema["auth_method"].ValidateFunc
+
+	_, errors := validate("token", "auth_method")
+	assert.Empty(errors)
+
+	_, errors = validate("tokn", "auth_method")
+	if assert.Len(errors, 1) {
+		assert.EqualError(errors[0], `"auth_method" must be one of basic, token, got "tokn". Did you mean "token"?`)
+	}
+}
+
+func TestValidateCVSSv3Severity(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, severity := range []float64{0, 4, 7, 9} {
+		_, errors := validateCVSSv3Severity(severity, "minimum_severity")
+		assert.Empty(errors)
+	}
+
+	_, errors := validateCVSSv3Severity(7.5, "minimum_severity")
+	if assert.Len(errors, 1) {
+		assert.EqualError(errors[0], `"minimum_severity" must be one of 0 (low), 4 (medium), 7 (high), 9 (critical), got 7.5. Did you mean high, 7?`)
+	}
+
+	_, errors = validateCVSSv3Severity(11.0, "minimum_severity")
+	if assert.Len(errors, 1) {
+		assert.EqualError(errors[0], `"minimum_severity" must be one of 0 (low), 4 (medium), 7 (high), 9 (critical), got 11`)
+	}
+}
+
+func TestUnknownRoleFailsPlan(t *testing.T) {
+	testResource(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccMachineUser_BasicConfig(acctest.RandString(8), "password", "admn", "basic"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Role admn does not exist on the Twistlock Console. Did you mean "admin"\?`),
+			},
+		},
+	})
+}
+
+func TestUnknownRoleForbiddenRoleList(t *testing.T) {
+	console := testFakeConsole(t)
+	console.InjectFault(&fakeconsole.Fault{Method: "GET", Path: "/rbac/roles", StatusCode: http.StatusForbidden, Body: `{"err": "forbidden"}`})
+	defer console.ClearFaults()
+
+	testResource(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccMachineUser_BasicConfig(acctest.RandString(8), "password", "admn", "basic"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Role admn is not a built-in role and the Twistlock Console's custom roles could not be listed to check it. Did you mean "admin"\?`),
+			},
+		},
+	})
+
+	c, err := client.NewClient(client.Config{Username: console.Username, Password: console.Password, BaseURL: console.URL()})
+	if assert.Nil(t, err) {
+		assert.Nil(t, checkUserRole(context.Background(), c, "policy-editor"), "custom roles are left to the Console")
+	}
+}